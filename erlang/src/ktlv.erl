@@ -75,7 +75,8 @@
                 ?list_of_uint8 | ?list_of_uint16 | ?list_of_uint24 |
                 ?list_of_uint32 | ?list_of_uint64 | ?list_of_double |
                 ?list_of_string | ?list_of_int8 | ?list_of_int16 |
-                ?list_of_int24 | ?list_of_int32 | ?list_of_int64.
+                ?list_of_int24 | ?list_of_int32 | ?list_of_int64 |
+                ?object | ?list_of_object.
 
 -type element() ::
         {key(), ?bool, value_bool()} |
@@ -103,7 +104,9 @@
         {key(), ?list_of_int24, [value_int24()]} |
         {key(), ?list_of_int32, [value_int32()]} |
         {key(), ?list_of_int64, [value_int64()]} |
-        {key(), ?list_of_double, [value_double()]}.
+        {key(), ?list_of_double, [value_double()]} |
+        {key(), ?object, [element()]} |
+        {key(), ?list_of_object, [[element()]]}.
 
 -type objectd() :: dict:dict(key(), objectd_element()).
 -type objectd_element() ::
@@ -127,7 +130,9 @@
         {?list_of_int24, [value_int24()]} |
         {?list_of_int32, [value_int32()]} |
         {?list_of_int64, [value_int64()]} |
-        {?list_of_double, [value_double()]}.
+        {?list_of_double, [value_double()]} |
+        {?object, [element()]} |
+        {?list_of_object, [[element()]]}.
 
 -type value_bool() :: 0 | 1.
 -type value_uint8() :: ?min_uint8..?max_uint8.
@@ -149,7 +154,8 @@
                  [value_uint8()] | [value_uint16()] | [value_uint24()] |
                  [value_uint32()] | [value_uint64()] | [value_double()] |
                  [value_string()] | [value_int8()] | [value_int16()] |
-                 [value_int24()] | [value_int32()] | [value_int64()].
+                 [value_int24()] | [value_int32()] | [value_int64()] |
+                 [element()] | [[element()]].
 
 %% ----------------------------------------------------------------------
 %% API functions
@@ -245,6 +251,13 @@ enc(?list_of_int64, V) ->
 enc(?list_of_double, V) ->
     Encoded = << <<I:64/float-big>> || I <- V>>,
     <<(size(Encoded)):16/unsigned-big, Encoded/binary>>;
+enc(?object, V) ->
+    Encoded = enc(V),
+    <<(size(Encoded)):16/unsigned-big, Encoded/binary>>;
+enc(?list_of_object, V) ->
+    Encoded = << <<(size(I)):16/unsigned-big, I/binary>> ||
+                  I <- [enc(O) || O <- V]>>,
+    <<(size(Encoded)):16/unsigned-big, Encoded/binary>>;
 enc(16#ff, Binary) ->
     %% only for testing purposes
     Size = size(Binary),
@@ -309,6 +322,12 @@ dec(?list_of_int64, Len, Tail) ->
 dec(?list_of_double, Len, Tail) ->
     {EncodedList, Tail2} = split_binary(Tail, Len),
     {[I || <<I:64/float-big>> <= EncodedList], Tail2};
+dec(?object, Len, Tail) ->
+    {Encoded, Tail2} = split_binary(Tail, Len),
+    {dec(Encoded), Tail2};
+dec(?list_of_object, Len, Tail) ->
+    {Encoded, Tail2} = split_binary(Tail, Len),
+    {[dec(I) || I <- dec_str_list_loop(Encoded)], Tail2};
 dec(_UnknownType, Len, Tail) ->
     {_Unknown, Tail2} = split_binary(Tail, Len),
     {Tail2}.
@@ -575,6 +594,22 @@ list_of_int64_test_() ->
                [0, ?min_int64, ?max_int64, ?min_int64 - 1, ?max_int64 + 1]))
     ].
 
+object_test_() ->
+    [?_assertMatch([], encdec(?object, [])),
+     ?_assertMatch([{1, ?uint8, 1}, {2, ?string, <<"abc">>}],
+                   encdec(?object, [{1, ?uint8, 1}, {2, ?string, <<"abc">>}])),
+     ?_assertMatch([{1, ?object, [{1, ?bool, 1}]}],
+                   encdec(?object, [{1, ?object, [{1, ?bool, 1}]}]))
+    ].
+
+list_of_object_test_() ->
+    [?_assertMatch([], encdec(?list_of_object, [])),
+     ?_assertMatch([[]], encdec(?list_of_object, [[]])),
+     ?_assertMatch([[{1, ?uint8, 1}], [], [{2, ?string, <<"a">>}]],
+                   encdec(?list_of_object,
+                          [[{1, ?uint8, 1}], [], [{2, ?string, <<"a">>}]]))
+    ].
+
 main_test_() ->
     [?_assertMatch([{1, ?bool, 1}], dec(enc([{1, ?bool, 1}]))),
      ?_assertMatch([{1, ?bool, 1},
@@ -0,0 +1,111 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Message of fixed width field types.
+var fixedList = List{
+	&Elem{1, Bool, true},
+	&Elem{2, Uint8, uint8(1)},
+	&Elem{3, Uint16, uint16(2)},
+	&Elem{4, Uint24, uint32(3)},
+	&Elem{5, Uint32, uint32(4)},
+	&Elem{6, Uint64, uint64(5)},
+	&Elem{7, Double, 6.5},
+	&Elem{8, Int8, int8(-1)},
+	&Elem{9, Int16, int16(-2)},
+	&Elem{10, Int24, int32(-3)},
+	&Elem{11, Int32, int32(-4)},
+	&Elem{12, Int64, int64(-5)},
+}
+
+func TestAppendTo(t *testing.T) {
+	list := append(List{
+		&Elem{13, String, "abc"},
+		&Elem{14, Object, List{&Elem{1, List_of_Uint8, []uint8{1}}}},
+		&Elem{15, List_of_Object, []List{{&Elem{1, Bool, false}}}},
+		&Elem{16, 200, Raw{1, 2}},
+	}, fixedList...)
+	prefix := []byte{0xca, 0xfe}
+	encoded, err := list.AppendTo(prefix)
+	if err != nil {
+		t.Fatalf("append: %s", err)
+	}
+	if !bytes.Equal(encoded[:2], prefix) {
+		t.Fatalf("prefix is lost: %v", encoded[:2])
+	}
+	decoded, err := DecodeList(encoded[2:])
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	for i, elem := range list {
+		if !elem.Equals(decoded[i]) {
+			t.Errorf("elems #%d differ: %v and %v", i, elem, decoded[i])
+		}
+	}
+	dict, err := list.Dict().AppendTo(nil)
+	if err != nil {
+		t.Fatalf("append: %s", err)
+	}
+	if len(dict) != len(encoded)-2 || !IsCanonical(dict) {
+		t.Errorf("unexpected dict encoding: %v", dict)
+	}
+	// failed append leaves dst intact
+	dst := []byte{1, 2, 3}
+	res, err := AppendElem(dst, &Elem{1, Uint8, "bad"})
+	if err == nil || !bytes.Equal(res, dst) {
+		t.Errorf("unexpected result: %v (%v)", res, err)
+	}
+	res, err = AppendElem(dst, &Elem{1, List_of_Uint8, make([]uint8, 0x10000)})
+	if !errors.Is(err, BodyTooLong) || !bytes.Equal(res, dst) {
+		t.Errorf("unexpected result: %v (%v)", res, err)
+	}
+	bad := List{&Elem{1, Uint8, uint8(1)}, &Elem{2, Uint8, "bad"}}
+	res, err = bad.AppendTo(dst)
+	if err == nil || !bytes.Equal(res, dst) {
+		t.Errorf("unexpected list result: %v (%v)", res, err)
+	}
+	res, err = bad.Dict().AppendTo(dst)
+	if err == nil || !bytes.Equal(res, dst) {
+		t.Errorf("unexpected dict result: %v (%v)", res, err)
+	}
+	res, err = Dict{1: &Elem{2, Uint8, uint8(1)}}.AppendTo(dst)
+	if err == nil || !bytes.Equal(res, dst) {
+		t.Errorf("unexpected dict result: %v (%v)", res, err)
+	}
+}
+
+func TestAppendToAllocs(t *testing.T) {
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := fixedList.AppendTo(buf[:0]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations but %v found", allocs)
+	}
+}
+
+func BenchmarkListEncode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := fixedList.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListAppendTo(b *testing.B) {
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if buf, err = fixedList.AppendTo(buf[:0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
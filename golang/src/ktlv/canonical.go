@@ -0,0 +1,101 @@
+package ktlv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Check if encoded message is in canonical form, i.e. it is
+// exactly what Dict.Encode (or Dict.EncodeChunked for values
+// longer than 65535 bytes) produces for the decoded message:
+//   - element keys are unique and sorted in ascending order;
+//   - Bool values are encoded as 0 or 1;
+//   - Bitmap has less than 8 unused bits and they are zero;
+//   - only values longer than 65535 bytes are chunked, all
+//     chunks but the last one are of maximum length;
+//   - nested objects are canonical too.
+//
+// Bodies of elements of unknown field types are not checked.
+func IsCanonical(b []byte) bool {
+	return checkCanonical(b, false) == nil
+}
+
+// Check if encoded message (or body of nested object) is
+// canonical. See IsCanonical.
+func checkCanonical(b []byte, nested bool) error {
+	prev := -1
+	for 0 < len(b) {
+		key, ftype, body, tail, err := ScanRaw(b)
+		if err != nil {
+			return err
+		}
+		if int(key) <= prev {
+			return fmt.Errorf("key#%d follows key#%d", key, prev)
+		}
+		prev = int(key)
+		if ftype == Chunk {
+			if nested {
+				return chunkNested(key)
+			}
+			if ftype, body, tail, err = joinChunks(key, body, tail); err != nil {
+				return err
+			}
+			if len(body) <= maxBodyLen {
+				return fmt.Errorf("key#%d: short value is chunked", key)
+			}
+			encoded := b[:len(b)-len(tail)]
+			if !bytes.Equal(encoded, appendChunks(nil, key, ftype, body)) {
+				return fmt.Errorf("key#%d: bad chunk lengths", key)
+			}
+		}
+		if err := checkCanonicalBody(ftype, body); err != nil {
+			return fmt.Errorf("key#%d: %w", key, err)
+		}
+		b = tail
+	}
+	return nil
+}
+
+// Check if element body is canonical. See IsCanonical.
+func checkCanonicalBody(ftype uint8, body []byte) error {
+	if !isKnown(ftype) {
+		return nil
+	}
+	switch ftype {
+	case Bool:
+		if len(body) == 1 && 1 < body[0] {
+			return fmt.Errorf("bad Bool: %d", body[0])
+		}
+	case Bitmap:
+		if len(body) == 0 {
+			break
+		}
+		unused := body[0]
+		if 7 < unused || (0 < unused && len(body) == 1) {
+			return fmt.Errorf("bad Bitmap unused bits: %d", unused)
+		}
+		if 0 < unused && body[1]>>(8-unused) != 0 {
+			return errors.New("Bitmap padding bits are set")
+		}
+	case Object:
+		return checkCanonical(body, true)
+	case List_of_Object:
+		for tail := body; 0 < len(tail); {
+			if len(tail) < 2 {
+				break // reported by decodeValue
+			}
+			l := int(binary.BigEndian.Uint16(tail))
+			if len(tail) < 2+l {
+				break // reported by decodeValue
+			}
+			if err := checkCanonical(tail[2:2+l], true); err != nil {
+				return err
+			}
+			tail = tail[2+l:]
+		}
+	}
+	_, err := decodeValue(ftype, body)
+	return err
+}
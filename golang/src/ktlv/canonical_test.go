@@ -0,0 +1,195 @@
+package ktlv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDictEncodeOrder(t *testing.T) {
+	dict := Dict{}
+	for _, key := range []uint16{9, 3, 65535, 0, 7, 1000} {
+		dict.Add(key, Uint16, key)
+	}
+	encoded, err := dict.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	list, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	for i, key := range []uint16{0, 3, 7, 9, 1000, 65535} {
+		if list[i].Key != key {
+			t.Errorf("#%d> expected key#%d but key#%d found",
+				i, key, list[i].Key)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		again, err := dict.Encode()
+		if err != nil {
+			t.Fatalf("encode: %s", err)
+		}
+		if !bytes.Equal(encoded, again) {
+			t.Fatalf("encoding is not deterministic")
+		}
+	}
+	if !IsCanonical(encoded) {
+		t.Errorf("encoded Dict is not canonical")
+	}
+}
+
+func TestDictEncodeCanonical(t *testing.T) {
+	unsorted := List{
+		&Elem{5, Uint8, uint8(6)},
+		&Elem{1, Object, List{&Elem{9, Bool, true}, &Elem{2, Bool, false}}},
+	}
+	dict := Dict{
+		1: {1, Object, unsorted},
+		2: {2, List_of_Object, []List{unsorted, {}}},
+		3: {3, Object, unsorted.Dict()},
+	}
+	encoded, err := dict.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if err := checkCanonical(encoded, false); err != nil {
+		t.Errorf("encoded Dict is not canonical: %s", err)
+	}
+	decoded, err := DecodeDict(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	expect := List{
+		&Elem{1, Object, List{&Elem{2, Bool, false}, &Elem{9, Bool, true}}},
+		&Elem{5, Uint8, uint8(6)},
+	}
+	if !decoded[1].Equals(&Elem{1, Object, expect}) {
+		t.Errorf("unexpected elem: %v", decoded[1])
+	}
+	// elements are not modified
+	if unsorted[0].Key != 5 || len(unsorted) != 2 {
+		t.Errorf("source list is modified: %v", unsorted)
+	}
+	// duplicate keys of nested objects can't be encoded canonically
+	duplicates := List{&Elem{5, Uint8, uint8(5)}, &Elem{5, Uint8, uint8(6)}}
+	for n, dict := range []Dict{
+		{1: {1, Object, duplicates}},
+		{2: {2, List_of_Object, []List{{}, duplicates}}},
+		{3: {3, Object, List{&Elem{1, Object, duplicates}}}},
+	} {
+		if _, err := dict.Encode(); err == nil {
+			t.Errorf("#%d> expected error for duplicate nested keys", n)
+		}
+	}
+	mismatch := Dict{5: {1, Uint8, uint8(1)}, 2: {2, Uint8, uint8(2)}}
+	if _, err := mismatch.Encode(); err == nil {
+		t.Errorf("expected error for mismatched keys")
+	}
+	nested := Dict{1: {1, Object, mismatch}}
+	if _, err := nested.Encode(); err == nil {
+		t.Errorf("expected error for mismatched nested keys")
+	}
+	s := Dict{3: {3, Uint8, uint8(3)}, 1: {1, Bool, true}, 2: {2, String, "a"}}.String()
+	if s != `1(Bool)=true,2(String)="a",3(Uint8)=0x3` {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
+
+func TestDictSorted(t *testing.T) {
+	dict := Dict{}
+	for _, key := range []uint16{9, 3, 65535, 0} {
+		dict.Add(key, Uint16, key)
+	}
+	keys := []uint16{}
+	for key, elem := range dict.Sorted() {
+		if elem != dict[key] {
+			t.Errorf("unexpected elem for key#%d: %v", key, elem)
+		}
+		keys = append(keys, key)
+		if key == 9 {
+			break
+		}
+	}
+	if len(keys) != 3 || keys[0] != 0 || keys[1] != 3 || keys[2] != 9 {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestIsCanonical(t *testing.T) {
+	long := strings.Repeat("a", 0x20000)
+	valid := []List{
+		{},
+		{&Elem{1, Bool, false}, &Elem{2, Bool, true}},
+		{&Elem{1, Bitmap, []bool{}}, &Elem{2, Bitmap, []bool{true}},
+			&Elem{3, Bitmap, make([]bool, 8)}},
+		{&Elem{1, String, long}, &Elem{2, String, "short"}},
+		{&Elem{1, Object, List{&Elem{1, Uint8, uint8(1)},
+			&Elem{2, Uint8, uint8(2)}}}},
+		{&Elem{1, List_of_Object, []List{{}, {&Elem{5, Bool, true}}}}},
+		{&Elem{1, 200, Raw{1, 2, 3}}},
+	}
+	for n, list := range valid {
+		encoded, err := list.EncodeChunked()
+		if err != nil {
+			t.Fatalf("#%d> encode: %s", n, err)
+		}
+		if !IsCanonical(encoded) {
+			t.Errorf("#%d> expected to be canonical", n)
+		}
+	}
+	// not chunked value followed by chunked short value
+	shortChunked := []byte{0, 1, Chunk, 0, 4, String, 1, 'a', 'b'}
+	invalid := [][]byte{
+		{0, 2, Uint8, 0, 1, 1, 0, 1, Uint8, 0, 1, 1},
+		{0, 1, Uint8, 0, 1, 1, 0, 1, Uint8, 0, 1, 1},
+		{0, 1, Bool, 0, 1, 2},
+		{0, 1, Bitmap, 0, 0},
+		{0, 1, Bitmap, 0, 1, 1},
+		{0, 1, Bitmap, 0, 2, 8, 0},
+		{0, 1, Bitmap, 0, 2, 7, 0x80},
+		{0, 1, Bitmap, 0, 2, 1, 0x80},
+		{0, 1, Uint16, 0, 1, 1},
+		shortChunked,
+		{0, 1, Object, 0, 12, 0, 2, Uint8, 0, 1, 1, 0, 1, Uint8, 0, 1, 1},
+		{0, 1, List_of_Object, 0, 8, 0, 6, 0, 1, Bool, 0, 1, 3},
+	}
+	for n, test := range invalid {
+		if IsCanonical(test) {
+			t.Errorf("#%d> expected to be non-canonical", n)
+		}
+	}
+	// chunked value with non-maximal first chunk
+	encoded, err := (&Elem{1, String, long}).EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	list, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	value := []byte(list[0].Value.(string))
+	resplit := []byte{}
+	for i, part := range [][]byte{value[:100], value[100:]} {
+		for 0 < len(part) {
+			l := len(part)
+			if maxChunkLen < l {
+				l = maxChunkLen
+			}
+			var last byte
+			if i == 1 && l == len(part) {
+				last = 1
+			}
+			resplit = append(resplit, 0, 1, Chunk,
+				byte((l+2)>>8), byte(l+2), String, last)
+			resplit = append(resplit, part[:l]...)
+			part = part[l:]
+		}
+	}
+	if _, err := DecodeList(resplit); err != nil {
+		t.Fatalf("decode resplit: %s", err)
+	}
+	if IsCanonical(resplit) {
+		t.Errorf("expected resplit chunks to be non-canonical")
+	}
+}
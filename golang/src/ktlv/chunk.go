@@ -0,0 +1,140 @@
+package ktlv
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// Max length of element body.
+	maxBodyLen = 0xffff
+	// Max length of value part carried by one Chunk element.
+	maxChunkLen = maxBodyLen - 2
+)
+
+// Encode data element to bytes as Encode does, but when encoded
+// value is longer than 65535 bytes, split it to several Chunk
+// elements with the same key. Body of each Chunk element starts
+// with the field type of the value and a flag which is set to 1
+// for the last chunk and to 0 for others. The rest of the body
+// is the next part of the encoded value.
+//
+// DecodeList, DecodeDict, DecodeElem, Search and Decoder join
+// chunks back to a single element transparently. Codecs not
+// aware of chunks skip them as elements of unknown type.
+// Only elements of the message itself are chunked: Chunk
+// elements within nested objects are malformed.
+func (e *Elem) EncodeChunked() ([]byte, error) {
+	return appendElemChunked(nil, e)
+}
+
+// Append encoded element to dst as AppendElem does, but split
+// values longer than 65535 bytes as EncodeChunked does.
+func appendElemChunked(dst []byte, e *Elem) ([]byte, error) {
+	res, mark := BeginElem(dst, e.Key, e.FType)
+	res, err := appendValue(res, e.FType, e.Value)
+	if err != nil {
+		return dst, fmt.Errorf("encode key#%d: %w", e.Key, err)
+	}
+	if body_len := len(res) - mark - 5; maxBodyLen < body_len {
+		// chunks take more space than the element, so the
+		// value has to be moved out of the way
+		body := append([]byte{}, res[mark+5:]...)
+		return appendChunks(res[:mark], e.Key, e.FType, body), nil
+	}
+	return EndElem(res, mark)
+}
+
+// Append value split to Chunk elements to dst.
+func appendChunks(dst []byte, key uint16, ftype uint8, body []byte) []byte {
+	for {
+		n := len(body)
+		last := uint8(1)
+		if maxChunkLen < n {
+			n = maxChunkLen
+			last = 0
+		}
+		var mark int
+		dst, mark = BeginElem(dst, key, Chunk)
+		dst = append(dst, ftype, last)
+		dst = append(dst, body[:n]...)
+		dst, _ = EndElem(dst, mark)
+		body = body[n:]
+		if last == 1 {
+			return dst
+		}
+	}
+}
+
+// Parse body of i-th Chunk element of chunked value. Field
+// type of the value is checked against the one found in previous
+// chunks (if any). Chunked values can't be chunked again.
+func parseChunk(key uint16, i int, ftype uint8, body []byte) (uint8, []byte, bool, error) {
+	if len(body) < 2 || 1 < body[1] || body[0] == Chunk ||
+		(0 < i && body[0] != ftype) {
+		return 0, nil, false, fmt.Errorf("%w: chunk #%d"+
+			" of elem key#%d", Malformed, i, key)
+	}
+	return body[0], body[2:], body[1] == 1, nil
+}
+
+// Error for Chunk element found within nested object.
+func chunkNested(key uint16) error {
+	return fmt.Errorf("%w: chunk of elem key#%d within nested"+
+		" object", Malformed, key)
+}
+
+// Error for chunked value interrupted by another element.
+func chunkInterrupted(key, next_key uint16, next_ftype uint8) error {
+	return fmt.Errorf("%w: chunked elem key#%d is interrupted"+
+		" by elem key#%d ftype=%d", Malformed, key, next_key, next_ftype)
+}
+
+// Join value split to Chunk elements. Takes body of the first
+// chunk and the rest of the message which must start with the
+// remaining chunks of the value. Returns field type and encoded
+// value along with the rest of the message after the last chunk.
+func joinChunks(key uint16, body, tail []byte) (ftype uint8, value, rest []byte, err error) {
+	ftype, rest, err = walkChunks(key, body, tail, func(part []byte) {
+		value = append(value, part...)
+	})
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return ftype, value, rest, nil
+}
+
+// Walk chunks of chunked value as joinChunks does, calling fn
+// for value part of each chunk (if fn is not nil).
+func walkChunks(key uint16, body, tail []byte, fn func([]byte)) (ftype uint8, rest []byte, err error) {
+	for i := 0; ; i++ {
+		var (
+			part []byte
+			last bool
+		)
+		if ftype, part, last, err = parseChunk(key, i, ftype, body); err != nil {
+			return 0, nil, err
+		}
+		if fn != nil {
+			fn(part)
+		}
+		if last {
+			return ftype, tail, nil
+		}
+		var (
+			next_key   uint16
+			next_ftype uint8
+		)
+		next_key, next_ftype, body, tail, err = ScanRaw(tail)
+		if err == io.EOF {
+			err = Truncated
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("incomplete chunked"+
+				" elem key#%d: %w", key, err)
+		}
+		if next_key != key || next_ftype != Chunk {
+			return 0, nil, chunkInterrupted(key, next_key, next_ftype)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBodyTooLong(t *testing.T) {
+	testset := []*Elem{
+		{1, String, strings.Repeat("a", 0x10000)},
+		{2, List_of_Uint8, make([]uint8, 0x10000)},
+		{3, List_of_Uint64, make([]uint64, 0x2000)},
+		{4, Object, List{{1, String, strings.Repeat("a", 0xfffb)}}},
+	}
+	for n, elem := range testset {
+		if _, err := elem.Encode(); !errors.Is(err, BodyTooLong) {
+			t.Errorf("#%d> expected BodyTooLong but %v found", n, err)
+		}
+		if _, err := elem.WriteTo(&bytes.Buffer{}); !errors.Is(err, BodyTooLong) {
+			t.Errorf("#%d> expected BodyTooLong but %v found", n, err)
+		}
+		if err := NewEncoder(&bytes.Buffer{}).Encode(elem); !errors.Is(err, BodyTooLong) {
+			t.Errorf("#%d> expected BodyTooLong but %v found", n, err)
+		}
+	}
+	if _, err := (&Elem{1, String, strings.Repeat("a", 0xffff)}).Encode(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	elem := &Elem{1, List_of_String, []string{strings.Repeat("a", 0x10000)}}
+	if _, err := elem.EncodeChunked(); !errors.Is(err, BodyTooLong) {
+		t.Errorf("expected BodyTooLong but %v found", err)
+	}
+}
+
+func TestEncodeChunked(t *testing.T) {
+	long := strings.Repeat("abcdefg", 40000)
+	list := List{
+		&Elem{1, Uint8, uint8(1)},
+		&Elem{2, String, long},
+		&Elem{3, String, "short"},
+		&Elem{4, List_of_Uint8, []uint8(long[:maxChunkLen])},
+		&Elem{5, List_of_String, []string{long[:0xffff], long[:0xffff]}},
+	}
+	encoded, err := list.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if _, err := list.Encode(); !errors.Is(err, BodyTooLong) {
+		t.Errorf("expected BodyTooLong but %v found", err)
+	}
+	decoded, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if len(decoded) != len(list) {
+		t.Fatalf("expected %d elements but %d found",
+			len(list), len(decoded))
+	}
+	for i, elem := range list {
+		if !elem.Equals(decoded[i]) {
+			t.Errorf("elems #%d differ", i)
+		}
+	}
+	if elem, err := DecodeElem(encoded, 2); err != nil || elem.Value != long {
+		t.Errorf("unexpected DecodeElem result: %v", err)
+	}
+	if elem, err := Search(encoded, 3, 3); err != nil || elem.Value != "short" {
+		t.Errorf("unexpected Search result: %v", err)
+	}
+	decoder := NewDecoder(bytes.NewReader(encoded))
+	for i := 0; ; i++ {
+		elem, err := decoder.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+		if !elem.Equals(list[i]) {
+			t.Errorf("stream elems #%d differ", i)
+		}
+	}
+	dict, err := list.Dict().EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if len(dict) != len(encoded) {
+		t.Errorf("expected %d bytes but %d found", len(encoded), len(dict))
+	}
+}
+
+func TestBrokenChunks(t *testing.T) {
+	encoded, err := (&Elem{1, String, strings.Repeat("a", 0x20000)}).EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	other, err := (&Elem{2, Uint8, uint8(1)}).Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	second := 5 + maxBodyLen
+	// type of the value in the second chunk is changed
+	badType := append([]byte{}, encoded...)
+	badType[second+5] = Uint8
+	// another element between chunks
+	interrupted := append(append(append([]byte{},
+		encoded[:second]...), other...), encoded[second:]...)
+	testset := [][]byte{
+		encoded[:second],
+		encoded[:len(encoded)-1],
+		badType,
+		interrupted,
+		{0, 1, Chunk, 0, 1, String},
+		{0, 1, Chunk, 0, 2, String, 2},
+	}
+	for n, test := range testset {
+		if _, err := DecodeList(test); err == nil {
+			t.Errorf("#%d> expected DecodeList error", n)
+		}
+		if _, err := DecodeElem(test, 1); err == nil {
+			t.Errorf("#%d> expected DecodeElem error", n)
+		}
+		if _, err := NewDecoder(bytes.NewReader(test)).Next(); err == nil {
+			t.Errorf("#%d> expected Decoder error", n)
+		}
+	}
+	// chunks are allowed at the top level only
+	nested := appendChunks(nil, 1, Object, encoded)
+	short := []byte{0, 1, Object, 0, 7, 0, 1, Chunk, 0, 2, Uint8, 1}
+	objects := []byte{0, 1, List_of_Object, 0, 9, 0, 7,
+		0, 1, Chunk, 0, 2, Uint8, 1}
+	for n, test := range [][]byte{nested, short, objects} {
+		if _, err := DecodeList(test); !errors.Is(err, Malformed) {
+			t.Errorf("#%d> expected Malformed but %v found", n, err)
+		}
+		if _, err := NewDecoder(bytes.NewReader(test)).Next(); !errors.Is(err, Malformed) {
+			t.Errorf("#%d> expected Malformed but %v found", n, err)
+		}
+		if IsCanonical(test) {
+			t.Errorf("#%d> unexpected canonical result", n)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"ktlv"
+)
+
+// Number of bytes in one row of hex view.
+const hexRowLen = 16
+
+// Decoding error with absolute offset within the message.
+type DumpError struct {
+	Offset int
+	Err    error
+}
+
+func (e *DumpError) Error() string {
+	return fmt.Sprintf("offset %d (0x%06x): %s", e.Offset, e.Offset, e.Err)
+}
+
+func (e *DumpError) Unwrap() error {
+	return e.Err
+}
+
+// Print each element of the encoded message to w. When hex is
+// true, each element is followed by hex view of its bytes. When
+// the message is broken, elements preceding the broken one are
+// printed along with hex view of the bytes starting from the
+// offset where decoding fails, and *DumpError is returned.
+func Dump(w io.Writer, b []byte, hex bool) error {
+	d := &dumper{w: w, input: b, hex: hex}
+	err := d.dump(b, 0, "")
+	if err, ok := err.(*DumpError); ok {
+		end := err.Offset + hexRowLen
+		if len(b) < end {
+			end = len(b)
+		}
+		fmt.Fprintf(w, "%06x  error: %s\n", err.Offset, err.Err)
+		d.hexRows("", ">>", err.Offset, end)
+	}
+	return err
+}
+
+type dumper struct {
+	w     io.Writer
+	input []byte
+	hex   bool
+}
+
+// Print elements of b which starts at offset base of the input.
+func (d *dumper) dump(b []byte, base int, indent string) error {
+	for offset := base; 0 < len(b); {
+		key, ftype, body, tail, err := ktlv.ScanRaw(b)
+		if err != nil {
+			return &DumpError{offset, err}
+		}
+		elem_len := len(b) - len(tail)
+		value, err := d.value(b[:elem_len], ftype, body)
+		if err != nil {
+			return &DumpError{offset + 5, err}
+		}
+		fmt.Fprintf(d.w, "%06x  %skey=%d %s len=%d%s\n",
+			offset, indent, key, typeName(ftype), len(body), value)
+		if d.hex {
+			d.hexRows(indent, "hdr ", offset, offset+5)
+			d.hexRows(indent, "body", offset+5, offset+elem_len)
+		}
+		switch ftype {
+		case ktlv.Object:
+			err = d.dump(body, offset+5, indent+"  ")
+		case ktlv.List_of_Object:
+			err = d.dumpObjects(body, offset+5, indent+"  ")
+		}
+		if err != nil {
+			return err
+		}
+		offset += elem_len
+		b = tail
+	}
+	return nil
+}
+
+// Print items of List_of_Object element body which starts at
+// offset base of the input.
+func (d *dumper) dumpObjects(b []byte, base int, indent string) error {
+	for i, offset := 0, base; 0 < len(b); i++ {
+		if len(b) < 2 {
+			return &DumpError{offset, fmt.Errorf("incomplete item length")}
+		}
+		item_len := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+item_len {
+			return &DumpError{offset, fmt.Errorf("broken item #%d."+
+				" expected len %d but %d found", i, item_len, len(b)-2)}
+		}
+		fmt.Fprintf(d.w, "%06x  %sitem #%d len=%d\n", offset, indent, i, item_len)
+		if err := d.dump(b[2:2+item_len], offset+2, indent+"  "); err != nil {
+			return err
+		}
+		offset += 2 + item_len
+		b = b[2+item_len:]
+	}
+	return nil
+}
+
+// Format decoded element value. Nested objects are not
+// formatted as they are printed element by element.
+func (d *dumper) value(elem []byte, ftype uint8, body []byte) (string, error) {
+	switch ftype {
+	case ktlv.Object, ktlv.List_of_Object:
+		return "", nil
+	case ktlv.Chunk:
+		if len(body) < 2 {
+			return "", fmt.Errorf("bad chunk len: %d", len(body))
+		}
+		s := fmt.Sprintf(" chunk of %s", typeName(body[0]))
+		if body[1] != 0 {
+			s += " (last)"
+		}
+		return s, nil
+	}
+	list, err := ktlv.DecodeList(elem)
+	if err != nil {
+		var decodeErr *ktlv.DecodeError
+		if errors.As(err, &decodeErr) {
+			// offset of DecodeError is relative to the element
+			err = fmt.Errorf("decode key#%d %s: %w", decodeErr.Key,
+				typeName(decodeErr.FType), decodeErr.Err)
+		}
+		return "", err
+	}
+	switch v := list[0].Value.(type) {
+	case string:
+		return fmt.Sprintf(" %q", v), nil
+	case []string:
+		return fmt.Sprintf(" %q", v), nil
+	case []bool:
+		bits := make([]byte, len(v))
+		for i, bit := range v {
+			bits[i] = '0'
+			if bit {
+				bits[i] = '1'
+			}
+		}
+		return fmt.Sprintf(" [%s]", bits), nil
+	case ktlv.Raw:
+		return fmt.Sprintf(" % x", []byte(v)), nil
+	}
+	return fmt.Sprintf(" %v", list[0].Value), nil
+}
+
+// Print input bytes from start to end in rows.
+func (d *dumper) hexRows(indent, label string, start, end int) {
+	blank := strings.Repeat(" ", len(label))
+	for offset := start; offset < end; offset += hexRowLen {
+		row_end := offset + hexRowLen
+		if end < row_end {
+			row_end = end
+		}
+		fmt.Fprintf(d.w, "%06x  %s  %s % x\n",
+			offset, indent, label, d.input[offset:row_end])
+		label = blank
+	}
+}
+
+// Return field type name, including service and unknown types.
+func typeName(ftype uint8) string {
+	if ftype == ktlv.Chunk {
+		return "Chunk"
+	}
+	if name := ktlv.FTypeToString(ftype); name != "" {
+		return name
+	}
+	return fmt.Sprintf("ftype=%d", ftype)
+}
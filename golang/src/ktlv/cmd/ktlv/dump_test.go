@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"ktlv"
+)
+
+func TestDump(t *testing.T) {
+	encoded, err := ktlv.List{
+		{Key: 1, FType: ktlv.String, Value: "hello"},
+		{Key: 2, FType: ktlv.Bitmap, Value: []bool{true, false, true}},
+		{Key: 3, FType: ktlv.List_of_Object, Value: []ktlv.List{
+			{{Key: 4, FType: ktlv.Uint8, Value: uint8(4)}}}},
+		{Key: 5, FType: 200, Value: ktlv.Raw{1, 2}},
+	}.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	out := &bytes.Buffer{}
+	if err := Dump(out, encoded, true); err != nil {
+		t.Fatalf("dump: %s", err)
+	}
+	expect := `000000  key=1 String len=5 "hello"
+000000    hdr  00 01 07 00 05
+000005    body 68 65 6c 6c 6f
+00000a  key=2 Bitmap len=2 [101]
+00000a    hdr  00 02 08 00 02
+00000f    body 05 05
+000011  key=3 List_of_Object len=8
+000011    hdr  00 03 3e 00 08
+000016    body 00 06 00 04 01 00 01 04
+000016    item #0 len=6
+000018      key=4 Uint8 len=1 4
+000018        hdr  00 04 01 00 01
+00001d        body 04
+00001e  key=5 ftype=200 len=2 01 02
+00001e    hdr  00 05 c8 00 02
+000023    body 01 02
+`
+	if out.String() != expect {
+		t.Errorf("unexpected dump:\n%s", out)
+	}
+}
+
+func TestDumpError(t *testing.T) {
+	testset := []struct {
+		input  []byte
+		offset int
+	}{
+		{[]byte{0, 1, ktlv.Uint8, 0, 1, 1, 0, 2}, 6},
+		{[]byte{0, 1, ktlv.Uint16, 0, 1, 1}, 5},
+		{[]byte{0, 1, ktlv.Object, 0, 6, 0, 1, ktlv.Uint8, 0, 2, 1}, 5},
+		{[]byte{0, 1, ktlv.List_of_Object, 0, 3, 0, 2, 1}, 5},
+	}
+	for n, test := range testset {
+		out := &bytes.Buffer{}
+		err := Dump(out, test.input, false)
+		var dumpErr *DumpError
+		if !errors.As(err, &dumpErr) || dumpErr.Offset != test.offset {
+			t.Errorf("#%d> expected error at %d but %v found",
+				n, test.offset, err)
+		}
+		if !strings.Contains(out.String(), "error: ") {
+			t.Errorf("#%d> error is not printed: %s", n, out)
+		}
+		if strings.Contains(out.String(), "at offset") {
+			t.Errorf("#%d> relative offset is printed: %s", n, out)
+		}
+	}
+}
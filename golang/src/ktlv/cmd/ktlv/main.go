@@ -0,0 +1,76 @@
+// Inspect KTLV-encoded messages.
+//
+// Usage:
+//
+//	ktlv dump [-x] [file]
+//
+// The dump subcommand reads a message from the file (or from
+// stdin when file is omitted or is "-") and prints each element
+// with its offset, key, field type name, body length and decoded
+// value. Nested objects are printed indented. With -x flag each
+// element is followed by annotated hex view of its header and
+// body bytes. When the message is broken, the offset where
+// decoding fails is reported and the command exits with status 1.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s dump [-x] [file]\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch flag.Arg(0) {
+	case "dump":
+		os.Exit(dumpCmd(flag.Args()[1:]))
+	default:
+		fmt.Fprintf(os.Stderr, "ktlv: unknown command %#v\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// Run dump subcommand. Returns exit status.
+func dumpCmd(args []string) int {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	hex := fs.Bool("x", false, "print annotated hex view of each element")
+	fs.Parse(args)
+	if 1 < fs.NArg() {
+		flag.Usage()
+		return 2
+	}
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ktlv: %s\n", err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ktlv: %s\n", err)
+		return 1
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	if err := Dump(w, input, *hex); err != nil {
+		w.Flush()
+		fmt.Fprintf(os.Stderr, "ktlv: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"ktlv"
+)
+
+// Go types for element values of each field type.
+var goTypes = map[uint8]string{
+	ktlv.Bool:           "bool",
+	ktlv.Uint8:          "uint8",
+	ktlv.Uint16:         "uint16",
+	ktlv.Uint24:         "uint32",
+	ktlv.Uint32:         "uint32",
+	ktlv.Uint64:         "uint64",
+	ktlv.Double:         "float64",
+	ktlv.String:         "string",
+	ktlv.Bitmap:         "[]bool",
+	ktlv.Int8:           "int8",
+	ktlv.Int16:          "int16",
+	ktlv.Int24:          "int32",
+	ktlv.Int32:          "int32",
+	ktlv.Int64:          "int64",
+	ktlv.List_of_String: "[]string",
+	ktlv.List_of_Uint8:  "[]uint8",
+	ktlv.List_of_Uint16: "[]uint16",
+	ktlv.List_of_Uint24: "[]uint32",
+	ktlv.List_of_Uint32: "[]uint32",
+	ktlv.List_of_Uint64: "[]uint64",
+	ktlv.List_of_Double: "[]float64",
+	ktlv.List_of_Int8:   "[]int8",
+	ktlv.List_of_Int16:  "[]int16",
+	ktlv.List_of_Int24:  "[]int32",
+	ktlv.List_of_Int32:  "[]int32",
+	ktlv.List_of_Int64:  "[]int64",
+	ktlv.Object:         "ktlv.List",
+	ktlv.List_of_Object: "[]ktlv.List",
+}
+
+// Range checks of values which typed Append* functions of the
+// ktlv package do not make, given as formats of conditions true
+// for out of range values.
+var rangeChecks = map[uint8]string{
+	ktlv.Uint24: "ktlv.Max_Uint24 < %[1]s",
+	ktlv.Int24:  "%[1]s < ktlv.Min_Int24 || ktlv.Max_Int24 < %[1]s",
+}
+
+// Item field types of list field types with range checks.
+var checkedItemTypes = map[uint8]uint8{
+	ktlv.List_of_Uint24: ktlv.Uint24,
+	ktlv.List_of_Int24:  ktlv.Int24,
+}
+
+// Name of the field type constant and suffix of typed
+// Append*/Decode* functions of the ktlv package.
+func typeNames(ftype uint8) (constant, suffix string) {
+	constant = ktlv.FTypeToString(ftype)
+	return constant, strings.Replace(constant, "_of_", "Of", 1)
+}
+
+// Generate formatted Go source for all messages of the schema.
+func Generate(schema *Schema, pkg string) ([]byte, error) {
+	if pkg == "" {
+		pkg = schema.Package
+	}
+	if pkg == "" {
+		return nil, fmt.Errorf("package name is not defined")
+	}
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by ktlvgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+	withFields := false
+	for _, m := range schema.Messages {
+		withFields = withFields || 0 < len(m.Fields)
+	}
+	if withFields {
+		fmt.Fprintf(b, "import (\n\t\"fmt\"\n\n\t\"ktlv\"\n)\n")
+	} else {
+		fmt.Fprintf(b, "import \"ktlv\"\n")
+	}
+	for _, m := range schema.Messages {
+		genMessage(b, m)
+	}
+	return format.Source(b.Bytes())
+}
+
+// Generate struct and its methods for one message.
+func genMessage(b *bytes.Buffer, m *Message) {
+	fmt.Fprintf(b, "\ntype %s struct {\n", m.Name)
+	for _, f := range m.Fields {
+		fmt.Fprintf(b, "\t%s %s\n", f.Name, goTypes[f.FType])
+	}
+	fmt.Fprintf(b, "}\n")
+
+	fmt.Fprintf(b, "\n// Encode %s to bytes.\n", m.Name)
+	fmt.Fprintf(b, "func (m *%s) Encode() ([]byte, error) {\n", m.Name)
+	fmt.Fprintf(b, "\treturn m.AppendTo(nil)\n}\n")
+
+	fmt.Fprintf(b, "\n// Append encoded %s to dst. On error returns dst unchanged.\n",
+		m.Name)
+	fmt.Fprintf(b, "func (m *%s) AppendTo(dst []byte) ([]byte, error) {\n",
+		m.Name)
+	if 0 < len(m.Fields) {
+		fmt.Fprintf(b, "\torig := dst\n")
+		fmt.Fprintf(b, "\tvar (\n\t\tmark int\n\t\terr error\n\t)\n")
+	}
+	for _, f := range m.Fields {
+		constant, suffix := typeNames(f.FType)
+		field := m.Name + "." + f.Name
+		if check, ok := rangeChecks[f.FType]; ok {
+			fmt.Fprintf(b, "\tif "+check+" {\n", "m."+f.Name)
+			fmt.Fprintf(b, "\t\treturn orig, fmt.Errorf(\"encode %s: %s overflow: %%d\", m.%s)\n",
+				field, constant, f.Name)
+			fmt.Fprintf(b, "\t}\n")
+		} else if item_type, ok := checkedItemTypes[f.FType]; ok {
+			fmt.Fprintf(b, "\tfor _, v := range m.%s {\n", f.Name)
+			fmt.Fprintf(b, "\t\tif "+rangeChecks[item_type]+" {\n", "v")
+			fmt.Fprintf(b, "\t\t\treturn orig, fmt.Errorf(\"encode %s: %s item overflow: %%d\", v)\n",
+				field, constant)
+			fmt.Fprintf(b, "\t\t}\n\t}\n")
+		}
+		fmt.Fprintf(b, "\tdst, mark = ktlv.BeginElem(dst, %d, ktlv.%s)\n",
+			f.Key, constant)
+		// objects are the only values which may fail to encode
+		object := ""
+		switch f.FType {
+		case ktlv.Object:
+			object = fmt.Sprintf("m.%s.AppendTo(dst)", f.Name)
+		case ktlv.List_of_Object:
+			object = fmt.Sprintf("ktlv.AppendListOfObject(dst, m.%s)", f.Name)
+		default:
+			fmt.Fprintf(b, "\tdst = ktlv.Append%s(dst, m.%s)\n", suffix, f.Name)
+		}
+		if object != "" {
+			fmt.Fprintf(b, "\tif dst, err = %s; err != nil {\n", object)
+			fmt.Fprintf(b, "\t\treturn orig, fmt.Errorf(\"encode %s: %%w\", err)\n", field)
+			fmt.Fprintf(b, "\t}\n")
+		}
+		fmt.Fprintf(b, "\tif dst, err = ktlv.EndElem(dst, mark); err != nil {\n")
+		fmt.Fprintf(b, "\t\treturn orig, fmt.Errorf(\"encode %s: %%w\", err)\n", field)
+		fmt.Fprintf(b, "\t}\n")
+	}
+	fmt.Fprintf(b, "\treturn dst, nil\n}\n")
+
+	fmt.Fprintf(b, "\n// Decode %s from bytes. Unknown elements are skipped,\n",
+		m.Name)
+	fmt.Fprintf(b, "// fields without elements are left intact.\n")
+	fmt.Fprintf(b, "func (m *%s) Decode(b []byte) error {\n", m.Name)
+	fmt.Fprintf(b, "\tc := ktlv.NewCursor(b)\n")
+	if len(m.Fields) == 0 {
+		fmt.Fprintf(b, "\tfor c.Next() {\n\t}\n\treturn c.Err()\n}\n")
+		return
+	}
+	fmt.Fprintf(b, "\tfor c.Next() {\n")
+	fmt.Fprintf(b, "\t\tswitch c.Key() {\n")
+	for _, f := range m.Fields {
+		constant, suffix := typeNames(f.FType)
+		field := m.Name + "." + f.Name
+		fmt.Fprintf(b, "\t\tcase %d:\n", f.Key)
+		fmt.Fprintf(b, "\t\t\tftype, body, err := c.RawValue()\n")
+		fmt.Fprintf(b, "\t\t\tif err != nil {\n\t\t\t\treturn err\n\t\t\t}\n")
+		fmt.Fprintf(b, "\t\t\tif ftype != ktlv.%s {\n", constant)
+		fmt.Fprintf(b, "\t\t\t\treturn fmt.Errorf(\"decode %s: %%w\",\n", field)
+		fmt.Fprintf(b, "\t\t\t\t\tktlv.TypeAssertionFailed)\n")
+		fmt.Fprintf(b, "\t\t\t}\n")
+		fmt.Fprintf(b, "\t\t\tif m.%s, err = ktlv.Decode%s(body); err != nil {\n",
+			f.Name, suffix)
+		fmt.Fprintf(b, "\t\t\t\treturn fmt.Errorf(\"decode %s: %%w\", err)\n", field)
+		fmt.Fprintf(b, "\t\t\t}\n")
+	}
+	fmt.Fprintf(b, "\t\t}\n\t}\n\treturn c.Err()\n}\n")
+}
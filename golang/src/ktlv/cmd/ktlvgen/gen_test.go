@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	code, err := Generate(schema, "")
+	if err != nil {
+		t.Fatalf("generate: %s", err)
+	}
+	for _, s := range []string{
+		"package example\n",
+		"type Person struct {",
+		"TagList []string",
+		"ktlv.BeginElem(dst, 10, ktlv.List_of_String)",
+		"ktlv.AppendListOfString(dst, m.TagList)",
+		"m.Age, err = ktlv.DecodeUint8(body)",
+		"func (m *Empty) Decode(b []byte) error {",
+	} {
+		if !bytes.Contains(code, []byte(s)) {
+			t.Errorf("%#v not found in generated code", s)
+		}
+	}
+	if code, err := Generate(schema, "other"); err != nil {
+		t.Errorf("generate: %s", err)
+	} else if !bytes.Contains(code, []byte("package other\n")) {
+		t.Errorf("package name is not overridden")
+	}
+	schema.Package = ""
+	if _, err := Generate(schema, ""); err == nil {
+		t.Errorf("expected error for undefined package")
+	}
+}
+
+const roundTripSchema = `
+package main
+
+message Record {
+	1 name String
+	2 count Uint24
+	3 delta Int24
+	4 bits Bitmap
+	5 values List_of_Int24
+	6 inner Object
+	7 items List_of_Object
+	8 blob List_of_Uint8
+}
+
+message Empty {
+}
+`
+
+// Test program for generated code: encodes and decodes records
+// and prints "ok" on success.
+const roundTripMain = `package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ktlv"
+)
+
+func check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		fmt.Printf(format+"\n", args...)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	r0 := Record{
+		Name:   "name",
+		Count:  ktlv.Max_Uint24,
+		Delta:  ktlv.Min_Int24,
+		Bits:   []bool{true, false, true},
+		Values: []int32{-1, 0, 1},
+		Inner:  ktlv.List{&ktlv.Elem{Key: 1, FType: ktlv.Bool, Value: true}},
+		Items:  []ktlv.List{{&ktlv.Elem{Key: 2, FType: ktlv.String, Value: "a"}}},
+		Blob:   []byte{1, 2, 3},
+	}
+	b, err := r0.Encode()
+	check(err == nil, "encode: %v", err)
+	var r1 Record
+	check(r1.Decode(b) == nil, "decode failed")
+	check(reflect.DeepEqual(r0, r1), "expected %+v but %+v found", r0, r1)
+	buf := make([]byte, 0, 2*len(b))
+	allocs := testing.AllocsPerRun(10, func() { r0.AppendTo(buf) })
+	check(allocs == 0, "expected no allocations but %v found", allocs)
+
+	// chunked fields and unknown elements
+	long := strings.Repeat("a", 0x10010)
+	b, err = ktlv.List{
+		&ktlv.Elem{Key: 1, FType: ktlv.String, Value: long},
+		&ktlv.Elem{Key: 100, FType: ktlv.Object, Value: ktlv.List{}},
+		&ktlv.Elem{Key: 8, FType: ktlv.List_of_Uint8, Value: []byte(long)},
+	}.EncodeChunked()
+	check(err == nil, "encode: %v", err)
+	var r2 Record
+	check(r2.Decode(b) == nil, "decode chunked failed")
+	check(r2.Name == long && string(r2.Blob) == long, "unexpected chunked values")
+
+	// overflow, type mismatch and broken input
+	orig := []byte{1, 2}
+	dst, err := (&Record{Count: ktlv.Max_Uint24 + 1}).AppendTo(orig)
+	check(err != nil && len(dst) == 2, "expected Uint24 overflow: %v", err)
+	b, _ = ktlv.List{&ktlv.Elem{Key: 2, FType: ktlv.Uint32, Value: uint32(1)}}.Encode()
+	err = r2.Decode(b)
+	check(errors.Is(err, ktlv.TypeAssertionFailed), "unexpected error: %v", err)
+	check(r2.Decode([]byte{0, 1, ktlv.String, 0, 5}) != nil, "expected decode error")
+
+	var e Empty
+	b, err = e.Encode()
+	check(err == nil && len(b) == 0, "encode empty: %v", err)
+	check(e.Decode(b) == nil, "decode empty failed")
+	fmt.Println("ok")
+}
+`
+
+func TestGenerateRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping build of generated code in short mode")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not found")
+	}
+	schema, err := ParseSchema(strings.NewReader(roundTripSchema))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	code, err := Generate(schema, "")
+	if err != nil {
+		t.Fatalf("generate: %s", err)
+	}
+	ktlvDir, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module roundtrip\n\ngo 1.23\n\n" +
+			"require ktlv v0.0.0\n\nreplace ktlv => " + ktlvDir + "\n",
+		"record.go": string(code),
+		"main.go":   roundTripMain,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil || string(out) != "ok\n" {
+		t.Errorf("generated code failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,59 @@
+// Generate Go code for KTLV messages described in schema file.
+//
+// Usage:
+//
+//	ktlvgen [-package name] [-o output.go] schema.ktlv
+//
+// See Schema type for the schema file syntax. For each message
+// the generated code contains a struct with Encode, AppendTo and
+// Decode methods.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	pkg := flag.String("package", "",
+		"Go package name (overrides package statement of the schema)")
+	output := flag.String("o", "", "output file (default is stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] schema.ktlv\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	schema, err := ParseSchema(file)
+	file.Close()
+	if err != nil {
+		fatal(fmt.Errorf("%s: %s", flag.Arg(0), err))
+	}
+	code, err := Generate(schema, *pkg)
+	if err != nil {
+		fatal(err)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(code)
+	} else {
+		err = ioutil.WriteFile(*output, code, 0644)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ktlvgen: %s\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"ktlv"
+)
+
+// Parsed schema file.
+//
+// Schema is a line oriented text. Everything after '#' up to
+// the end of line is a comment. Example:
+//
+//	package example
+//
+//	message Person {
+//		1 name String
+//		2 age Uint8
+//		3 tags List_of_String
+//	}
+//
+// Package statement is optional. Each message field is
+// described with element key, field name and field type name
+// (as returned by ktlv.FTypeToString, case insensitive). Object
+// and List_of_Object fields are ktlv.List and []ktlv.List.
+// Field names are converted to CamelCase Go names, which must not
+// clash with generated Encode, AppendTo and Decode methods.
+type Schema struct {
+	Package  string
+	Messages []*Message
+}
+
+type Message struct {
+	Name   string
+	Fields []*Field
+}
+
+type Field struct {
+	Key   uint16
+	Name  string
+	FType uint8
+}
+
+// Parse schema from reader.
+func ParseSchema(r io.Reader) (*Schema, error) {
+	schema := &Schema{}
+	var msg *Message
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); 0 <= i {
+			line = line[:i]
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		var err error
+		switch {
+		case msg != nil && len(tokens) == 1 && tokens[0] == "}":
+			schema.Messages = append(schema.Messages, msg)
+			msg = nil
+		case msg != nil:
+			err = msg.parseField(tokens)
+		case tokens[0] == "package" && len(tokens) == 2:
+			if schema.Package != "" {
+				err = fmt.Errorf("duplicate package statement")
+			} else if !isIdent(tokens[1]) {
+				err = fmt.Errorf("bad package name: %#v", tokens[1])
+			}
+			schema.Package = tokens[1]
+		case tokens[0] == "message" && len(tokens) == 3 &&
+			tokens[2] == "{":
+			msg, err = schema.newMessage(tokens[1])
+		default:
+			err = fmt.Errorf("syntax error")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineno, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if msg != nil {
+		return nil, fmt.Errorf("message %s is not closed", msg.Name)
+	}
+	return schema, nil
+}
+
+// Start new message definition.
+func (s *Schema) newMessage(name string) (*Message, error) {
+	if !isIdent(name) {
+		return nil, fmt.Errorf("bad message name: %#v", name)
+	}
+	name = goName(name)
+	for _, m := range s.Messages {
+		if m.Name == name {
+			return nil, fmt.Errorf("duplicate message: %s", name)
+		}
+	}
+	return &Message{Name: name}, nil
+}
+
+// Parse message field definition.
+func (m *Message) parseField(tokens []string) error {
+	if len(tokens) != 3 {
+		return fmt.Errorf("syntax error")
+	}
+	key, err := strconv.ParseUint(tokens[0], 10, 16)
+	if err != nil {
+		return fmt.Errorf("bad key: %#v", tokens[0])
+	}
+	if !isIdent(tokens[1]) {
+		return fmt.Errorf("bad field name: %#v", tokens[1])
+	}
+	ftype, ok := ktlv.StringToFType(tokens[2])
+	if !ok {
+		return fmt.Errorf("unknown field type: %#v", tokens[2])
+	}
+	if _, ok := goTypes[ftype]; !ok {
+		return fmt.Errorf("unsupported field type: %s", tokens[2])
+	}
+	field := &Field{uint16(key), goName(tokens[1]), ftype}
+	if methodNames[field.Name] {
+		return fmt.Errorf("field name %#v clashes with generated"+
+			" method %s", tokens[1], field.Name)
+	}
+	for _, f := range m.Fields {
+		if f.Key == field.Key {
+			return fmt.Errorf("duplicate key: %d", f.Key)
+		}
+		if f.Name == field.Name {
+			return fmt.Errorf("duplicate field: %s", f.Name)
+		}
+	}
+	m.Fields = append(m.Fields, field)
+	return nil
+}
+
+// Names of methods generated for messages, which can't be used
+// as field names.
+var methodNames = map[string]bool{
+	"Encode":   true,
+	"AppendTo": true,
+	"Decode":   true,
+}
+
+// Check if string is valid identifier starting with a letter
+// (ASCII only).
+func isIdent(s string) bool {
+	for i, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case ('0' <= c && c <= '9' || c == '_') && 0 < i:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Convert snake_case identifier to exported Go name.
+func goName(s string) string {
+	res := ""
+	for _, word := range strings.Split(s, "_") {
+		if word != "" {
+			res += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"ktlv"
+)
+
+const testSchema = `
+package example # comment
+
+message person {
+	1 name String
+	2 age uint8
+	10 tag_list List_of_String
+}
+
+message Empty {
+}
+`
+
+func TestParseSchema(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if schema.Package != "example" || len(schema.Messages) != 2 {
+		t.Fatalf("unexpected schema: %+v", schema)
+	}
+	m := schema.Messages[0]
+	if m.Name != "Person" || len(m.Fields) != 3 {
+		t.Fatalf("unexpected message: %+v", m)
+	}
+	f := m.Fields[2]
+	if f.Key != 10 || f.Name != "TagList" || f.FType != ktlv.List_of_String {
+		t.Errorf("unexpected field: %+v", f)
+	}
+	if len(schema.Messages[1].Fields) != 0 {
+		t.Errorf("unexpected message: %+v", schema.Messages[1])
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	testset := []string{
+		"foo",
+		"package 1a",
+		"package a\npackage b",
+		"message A {",
+		"message A {\n}\nmessage A {\n}",
+		"message A {\n1 a Foo\n}",
+		"message A {\n65536 a Bool\n}",
+		"message A {\n1 a Bool\n1 b Bool\n}",
+		"message A {\n1 a Bool\n2 a Bool\n}",
+		"message A {\n1 _a Bool\n}",
+		"message A {\n1 a\n}",
+		"message A {\n1 encode Bool\n}",
+		"message A {\n1 append_to Bool\n}",
+		"message A {\n1 decode Bool\n}",
+	}
+	for n, test := range testset {
+		if _, err := ParseSchema(strings.NewReader(test)); err == nil {
+			t.Errorf("#%d> expected error but parse succeeded", n)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package ktlv
+
+import "iter"
+
+// Cursor walks over elements of KTLV-encoded message parsing
+// element headers only. Values are decoded on demand, so
+// elements can be inspected without decoding the ones not
+// needed. Chunked value (see Elem.EncodeChunked) is seen as one
+// element of Chunk field type.
+//
+//	c := NewCursor(encoded)
+//	for c.Next() {
+//		if c.Key() == 7 {
+//			value, err := c.Value()
+//			...
+//		}
+//	}
+//	if err := c.Err(); err != nil {
+//		...
+//	}
+type Cursor struct {
+	b []byte
+	l limiter
+	// the current element
+	cur    bool
+	offset int
+	key    uint16
+	ftype  uint8
+	body   []byte
+	// rest of the message after the current element header and
+	// body or after all its chunks when done is set
+	tail []byte
+	done bool
+	// set for body of nested object, where chunks are not allowed
+	nested bool
+	// decoded current element, so that it is decoded and counted
+	// towards the limits once
+	value    *Elem
+	valueErr error
+	decoded  bool
+	err      error
+}
+
+// Create new cursor over KTLV-encoded message. The cursor is
+// positioned before the first element.
+func NewCursor(b []byte) *Cursor {
+	return &Cursor{b: b, tail: b}
+}
+
+// Create new cursor as NewCursor does, but enforce the limits
+// when values are decoded.
+func (o *DecodeOptions) NewCursor(b []byte) *Cursor {
+	c := &Cursor{b: b, l: limiter{opts: o}, tail: b}
+	c.err = o.checkLen(b)
+	return c
+}
+
+// Iterate over keys and decoded elements of KTLV-encoded
+// message. Decode error stops the iteration; it is returned by
+// the second result called after the loop.
+//
+//	elems, errf := Elements(encoded)
+//	for key, elem := range elems {
+//		...
+//	}
+//	if err := errf(); err != nil {
+//		...
+//	}
+func Elements(encoded []byte) (iter.Seq2[uint16, *Elem], func() error) {
+	return noLimits.Elements(encoded)
+}
+
+// Iterate over elements as Elements does, but enforce the limits.
+func (o *DecodeOptions) Elements(encoded []byte) (iter.Seq2[uint16, *Elem], func() error) {
+	var err error
+	seq := func(yield func(uint16, *Elem) bool) {
+		c := o.NewCursor(encoded)
+		for key, elem := range c.elems() {
+			if !yield(key, elem) {
+				break
+			}
+		}
+		err = c.Err()
+	}
+	return seq, func() error { return err }
+}
+
+// Iterate over the rest elements decoding them. Decode error
+// stops the cursor (see Err).
+func (c *Cursor) elems() iter.Seq2[uint16, *Elem] {
+	return func(yield func(uint16, *Elem) bool) {
+		for c.Next() {
+			elem, err := c.elem()
+			if err != nil {
+				c.cur = false
+				c.err = err
+				return
+			}
+			if !yield(elem.Key, elem) {
+				return
+			}
+		}
+	}
+}
+
+// Move to the next element skipping the current one. Returns
+// false when the message ends or the next element header can not
+// be parsed (see Err).
+func (c *Cursor) Next() bool {
+	if c.err != nil || c.Skip() != nil {
+		return false
+	}
+	c.cur = false
+	if len(c.tail) == 0 {
+		return false
+	}
+	c.offset = len(c.b) - len(c.tail)
+	key, ftype, body, tail, err := ScanRaw(c.tail)
+	if err != nil {
+		c.l.offset = c.offset
+		c.err = c.l.error(key, ftype, err)
+		return false
+	}
+	if c.nested && ftype == Chunk {
+		c.l.offset = c.offset
+		c.err = c.l.error(key, ftype, chunkNested(key))
+		return false
+	}
+	c.cur = true
+	c.key, c.ftype, c.body, c.tail = key, ftype, body, tail
+	c.done = false
+	c.value, c.valueErr, c.decoded = nil, nil, false
+	return true
+}
+
+// Return error stopped the cursor. Returns nil when the cursor
+// reached the end of the message.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Key of the current element.
+func (c *Cursor) Key() uint16 {
+	return c.key
+}
+
+// Field type of the current element.
+func (c *Cursor) FType() uint8 {
+	return c.ftype
+}
+
+// Body length of the current element. For chunked value it is
+// body length of the first chunk.
+func (c *Cursor) Len() int {
+	return len(c.body)
+}
+
+// Offset of the current element within the message.
+func (c *Cursor) Offset() int {
+	return c.offset
+}
+
+// Body of the current element as is. For chunked value it is
+// body of the first chunk. Returned slice shares memory with
+// the message.
+func (c *Cursor) RawBody() []byte {
+	return c.body
+}
+
+// Field type and encoded value of the current element without
+// decoding it. Chunks of chunked value are joined to a new slice
+// and the original field type is returned, otherwise the value
+// is the element body shared with the message (see RawBody).
+// Broken chunks stop the cursor and the error is returned (see
+// Err).
+func (c *Cursor) RawValue() (uint8, []byte, error) {
+	if !c.cur {
+		return 0, nil, ElementNotFound
+	}
+	if c.ftype != Chunk {
+		c.done = true
+		return c.ftype, c.body, nil
+	}
+	// the tail is moved past all chunks once they are skipped
+	tail := c.b[c.offset+5+len(c.body):]
+	ftype, value, rest, err := joinChunks(c.key, c.body, tail)
+	if err != nil {
+		c.cur = false
+		c.err = &DecodeError{c.offset, c.key, Chunk, err}
+		return 0, nil, c.err
+	}
+	c.l.offset = c.offset
+	if err := c.l.join(len(value)); err != nil {
+		return 0, nil, c.l.error(c.key, ftype, err)
+	}
+	c.tail = rest
+	c.done = true
+	return ftype, value, nil
+}
+
+// Decode value of the current element. Chunks of chunked value
+// are joined and decoded as one value of the original field
+// type. Malformed value is reported with *DecodeError but does
+// not stop the cursor. Repeated calls return the same value.
+func (c *Cursor) Value() (interface{}, error) {
+	elem, err := c.elem()
+	if err != nil {
+		return nil, err
+	}
+	return elem.Value, nil
+}
+
+// Skip the current element. For chunked value all its chunks
+// are checked and skipped. Broken chunks stop the cursor and
+// the error is returned (see Err). Next skips the current
+// element implicitly.
+func (c *Cursor) Skip() error {
+	if !c.cur || c.done {
+		return c.err
+	}
+	if c.ftype == Chunk {
+		_, rest, err := walkChunks(c.key, c.body, c.tail, nil)
+		if err != nil {
+			c.cur = false
+			c.err = &DecodeError{c.offset, c.key, Chunk, err}
+			return c.err
+		}
+		c.tail = rest
+	}
+	c.done = true
+	return nil
+}
+
+// Offset of the message byte right after the current element.
+// Valid only when the current element is done.
+func (c *Cursor) end() int {
+	return len(c.b) - len(c.tail)
+}
+
+// Decode the current element once.
+func (c *Cursor) elem() (*Elem, error) {
+	if !c.cur {
+		return nil, ElementNotFound
+	}
+	if !c.decoded {
+		c.value, c.valueErr = c.scan()
+		c.decoded = true
+	}
+	return c.value, c.valueErr
+}
+
+// Decode the current element.
+func (c *Cursor) scan() (*Elem, error) {
+	c.l.offset = c.offset
+	elem, tail, err := c.l.scan(c.b[c.offset:])
+	if err != nil {
+		return nil, err
+	}
+	c.tail = tail
+	c.done = true
+	return elem, nil
+}
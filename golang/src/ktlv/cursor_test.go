@@ -0,0 +1,224 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	long := strings.Repeat("a", 0x10010)
+	encoded, err := List{
+		&Elem{1, Uint16, uint16(1)},
+		&Elem{2, String, long},
+		&Elem{3, Object, List{&Elem{1, Bool, true}}},
+		&Elem{4, String, "abc"},
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	testset := []struct {
+		key    uint16
+		ftype  uint8
+		len    int
+		offset int
+		value  *Elem // nil to skip
+	}{
+		{1, Uint16, 2, 0, &Elem{1, Uint16, uint16(1)}},
+		{2, Chunk, maxBodyLen, 7, nil},
+		{3, Object, 6, len(encoded) - 19, &Elem{3, Object, List{&Elem{1, Bool, true}}}},
+		{4, String, 3, len(encoded) - 8, nil},
+	}
+	c := NewCursor(encoded)
+	for n, test := range testset {
+		if !c.Next() {
+			t.Fatalf("#%d> unexpected end: %v", n, c.Err())
+		}
+		if c.Key() != test.key || c.FType() != test.ftype ||
+			c.Len() != test.len || c.Offset() != test.offset {
+			t.Errorf("#%d> unexpected header: key#%d ftype=%d len=%d"+
+				" offset=%d", n, c.Key(), c.FType(), c.Len(), c.Offset())
+		}
+		if !bytes.Equal(c.RawBody(), encoded[c.Offset()+5:c.Offset()+5+c.Len()]) {
+			t.Errorf("#%d> unexpected raw body", n)
+		}
+		if test.value == nil {
+			continue
+		}
+		value, err := c.Value()
+		if err != nil {
+			t.Errorf("#%d> unexpected error: %s", n, err)
+		} else if !test.value.Equals(&Elem{c.Key(), c.FType(), value}) {
+			t.Errorf("#%d> unexpected value: %v", n, value)
+		}
+	}
+	if c.Next() || c.Err() != nil {
+		t.Errorf("expected end but %v found", c.Err())
+	}
+	// chunked value is decoded as a whole
+	c = NewCursor(encoded)
+	if !c.Next() || c.Skip() != nil || !c.Next() {
+		t.Fatalf("unexpected error: %v", c.Err())
+	}
+	if value, err := c.Value(); err != nil || value != long {
+		t.Errorf("unexpected result: %v", err)
+	}
+	if !c.Next() || c.Key() != 3 {
+		t.Errorf("expected key#3 but key#%d found", c.Key())
+	}
+	// raw values of chunked value are joined, after Skip too
+	c = NewCursor(encoded)
+	for c.Next() && c.Key() != 4 {
+		if c.Key() == 2 {
+			c.Skip()
+		}
+		ftype, body, err := c.RawValue()
+		if err != nil {
+			t.Errorf("key#%d> unexpected error: %s", c.Key(), err)
+		} else if c.Key() == 2 && (ftype != String || string(body) != long) {
+			t.Errorf("unexpected chunked raw value: %d %d bytes", ftype, len(body))
+		} else if c.Key() == 1 && (ftype != Uint16 || !bytes.Equal(body, c.RawBody())) {
+			t.Errorf("unexpected raw value: %d %v", ftype, body)
+		}
+	}
+	if c.Key() != 4 {
+		t.Errorf("expected key#4 but key#%d found: %v", c.Key(), c.Err())
+	}
+}
+
+func TestCursorErrors(t *testing.T) {
+	encoded, err := List{
+		&Elem{1, Uint16, uint16(1)},
+		&Elem{2, String, strings.Repeat("a", 0x10010)},
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	// malformed value does not stop the cursor
+	c := NewCursor(append([]byte{0, 1, Uint16, 0, 1, 1}, encoded[7:]...))
+	if !c.Next() {
+		t.Fatalf("unexpected end: %v", c.Err())
+	}
+	if _, err := c.Value(); !errors.Is(err, BadLength) {
+		t.Errorf("expected BadLength but %v found", err)
+	}
+	if !c.Next() || c.Key() != 2 || c.Offset() != 6 {
+		t.Errorf("unexpected cursor state: key#%d (%v)", c.Key(), c.Err())
+	}
+	// broken chunks stop the cursor
+	c = NewCursor(encoded[:len(encoded)-1])
+	if !c.Next() || !c.Next() {
+		t.Fatalf("unexpected end: %v", c.Err())
+	}
+	var derr *DecodeError
+	if err := c.Skip(); !errors.As(err, &derr) || derr.Offset != 7 ||
+		!errors.Is(err, Truncated) {
+		t.Errorf("unexpected skip error: %v", err)
+	}
+	if c.Next() || c.Err() == nil {
+		t.Errorf("expected error")
+	}
+	// truncated header
+	c = NewCursor(encoded[:9])
+	if !c.Next() || c.Next() || !errors.As(c.Err(), &derr) ||
+		derr.Offset != 7 || !errors.Is(derr, Truncated) {
+		t.Errorf("unexpected error: %v", c.Err())
+	}
+	if _, err := c.Value(); err != ElementNotFound {
+		t.Errorf("expected ElementNotFound but %v found", err)
+	}
+	// limits are checked when value is decoded
+	c = (&DecodeOptions{MaxStringLen: 3}).NewCursor(encoded)
+	if !c.Next() || !c.Next() {
+		t.Fatalf("unexpected end: %v", c.Err())
+	}
+	if _, err := c.Value(); !errors.Is(err, StringTooLong) {
+		t.Errorf("expected StringTooLong but %v found", err)
+	}
+	c = (&DecodeOptions{MaxBytes: 3}).NewCursor(encoded)
+	if c.Next() || !errors.Is(c.Err(), MessageTooLong) {
+		t.Errorf("expected MessageTooLong but %v found", c.Err())
+	}
+	// element decoded several times is counted once
+	c = (&DecodeOptions{MaxElements: 1}).NewCursor(encoded)
+	if !c.Next() {
+		t.Fatalf("unexpected end: %v", c.Err())
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Value(); err != nil {
+			t.Errorf("#%d> unexpected error: %v", i, err)
+		}
+	}
+	if !c.Next() {
+		t.Fatalf("unexpected end: %v", c.Err())
+	}
+	if _, err := c.Value(); !errors.Is(err, TooManyElements) {
+		t.Errorf("expected TooManyElements but %v found", err)
+	}
+}
+
+func TestElements(t *testing.T) {
+	data := List{
+		&Elem{1, Uint16, uint16(1)},
+		&Elem{2, String, strings.Repeat("a", 0x10010)},
+		&Elem{3, Bool, true},
+	}
+	encoded, err := data.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	n := 0
+	elems, errf := Elements(encoded)
+	for key, elem := range elems {
+		if key != data[n].Key || !elem.Equals(data[n]) {
+			t.Errorf("#%d> unexpected elem: %d %v", n, key, elem)
+		}
+		n++
+	}
+	if err := errf(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if n != len(data) {
+		t.Errorf("expected %d elems but %d found", len(data), n)
+	}
+	// break stops the iteration
+	for key := range elems {
+		if key != 1 {
+			t.Errorf("unexpected key: %d", key)
+		}
+		break
+	}
+	if err := errf(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// error stops the iteration
+	broken := append(append([]byte{}, encoded[:7]...), 0, 4, Uint16, 0, 1, 1)
+	testset := [][]byte{broken, broken[:9]}
+	for n, test := range testset {
+		var keys []uint16
+		elems, errf := Elements(test)
+		for key := range elems {
+			keys = append(keys, key)
+		}
+		if len(keys) != 1 || keys[0] != 1 || errf() == nil {
+			t.Errorf("#%d> unexpected result: %v (%v)", n, keys, errf())
+		}
+	}
+	elems, errf = Elements(nil)
+	for range elems {
+		t.Errorf("unexpected iteration over empty message")
+	}
+	if err := errf(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	opts := DecodeOptions{MaxStringLen: 3}
+	keys := []uint16{}
+	elems, errf = opts.Elements(encoded)
+	for key := range elems {
+		keys = append(keys, key)
+	}
+	if len(keys) != 1 || !errors.Is(errf(), StringTooLong) {
+		t.Errorf("expected StringTooLong but %v (%v) found", errf(), keys)
+	}
+}
@@ -0,0 +1,154 @@
+package ktlv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads and decodes elements one by one from an input
+// stream. It never buffers more than one element (or all chunks
+// of one chunked element, see Elem.EncodeChunked). Use
+// DecodeOptions.NewDecoder to bound the buffered size for
+// untrusted streams.
+type Decoder struct {
+	r      io.Reader
+	opts   *DecodeOptions
+	header [5]byte
+	body   []byte
+	// number of bytes read from the stream
+	offset int
+}
+
+// Create new decoder reading from r. Decoder makes no more
+// reads than needed to get the next element, so wrap r with
+// bufio.Reader to reduce the number of reads when appropriate.
+func NewDecoder(r io.Reader) *Decoder {
+	return noLimits.NewDecoder(r)
+}
+
+// Create new decoder as NewDecoder does, but enforce the limits
+// for each element read from the stream: MaxBytes bounds encoded
+// length of the element including all its chunks, the other
+// limits apply to its value. ZeroCopy is ignored as the decoder
+// reuses its buffer.
+func (o *DecodeOptions) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, opts: o}
+}
+
+// Read and decode next element from the stream.
+// Returns io.EOF when stream ends at element boundary and
+// io.ErrUnexpectedEOF when stream ends in the middle of element.
+// Malformed elements are reported with *DecodeError, where offset
+// is counted from the first byte read by the decoder.
+func (d *Decoder) Next() (*Elem, error) {
+	offset := d.offset
+	key, ftype, body, err := d.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkLen(5 + len(body)); err != nil {
+		return nil, &DecodeError{offset, key, ftype, err}
+	}
+	chunked := ftype == Chunk
+	if chunked {
+		if ftype, body, err = d.readChunks(key, body); err != nil {
+			if errors.Is(err, Malformed) || errors.Is(err, MessageTooLong) {
+				return nil, &DecodeError{offset, key, Chunk, err}
+			}
+			return nil, err
+		}
+	} else if ftype == Object || ftype == List_of_Object {
+		// nested values may refer to the body, so it can't be reused
+		body = append([]byte{}, body...)
+	}
+	l := &limiter{opts: d.opts}
+	if err := l.check(key, ftype, body, offset+5); err != nil {
+		return nil, &DecodeError{offset, key, ftype, err}
+	}
+	elem, err := decodeElem(key, ftype, body, false)
+	if err != nil {
+		if err, ok := err.(*DecodeError); ok && !chunked {
+			err.Offset += offset + 5
+			return nil, err
+		}
+		return nil, &DecodeError{offset, key, ftype, err}
+	}
+	// body buffer is reused, so values must not refer to it
+	switch v := elem.Value.(type) {
+	case []uint8:
+		elem.Value = append(make([]uint8, 0, len(v)), v...)
+	case Raw:
+		elem.Value = append(make(Raw, 0, len(v)), v...)
+	}
+	return elem, nil
+}
+
+// Read next element from the stream without decoding its body.
+// Returned body is valid only until the next read.
+func (d *Decoder) read() (key uint16, ftype uint8, body []byte, err error) {
+	if _, err := io.ReadFull(d.r, d.header[:]); err != nil {
+		return 0, 0, nil, err
+	}
+	key = binary.BigEndian.Uint16(d.header[0:])
+	ftype = d.header[2]
+	body_len := int(binary.BigEndian.Uint16(d.header[3:]))
+	if cap(d.body) < body_len {
+		d.body = make([]byte, body_len)
+	}
+	body = d.body[:body_len]
+	if _, err := io.ReadFull(d.r, body); err != nil {
+		if err == io.EOF {
+			return 0, 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, 0, nil, err
+	}
+	d.offset += 5 + body_len
+	return key, ftype, body, nil
+}
+
+// Read the rest chunks of chunked element and join them.
+func (d *Decoder) readChunks(key uint16, body []byte) (ftype uint8, value []byte, err error) {
+	size := 0
+	for i := 0; ; i++ {
+		var (
+			part []byte
+			last bool
+		)
+		if ftype, part, last, err = parseChunk(key, i, ftype, body); err != nil {
+			return 0, nil, err
+		}
+		value = append(value, part...)
+		if last {
+			return ftype, value, nil
+		}
+		size += 5 + len(body)
+		var (
+			next_key   uint16
+			next_ftype uint8
+		)
+		next_key, next_ftype, body, err = d.read()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		if next_key != key || next_ftype != Chunk {
+			return 0, nil, chunkInterrupted(key, next_key, next_ftype)
+		}
+		if err := d.checkLen(size + 5 + len(body)); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// Check encoded length of the element being read.
+func (d *Decoder) checkLen(n int) error {
+	if 0 < d.opts.MaxBytes && d.opts.MaxBytes < n {
+		return fmt.Errorf("%w: more than %d bytes", MessageTooLong,
+			d.opts.MaxBytes)
+	}
+	return nil
+}
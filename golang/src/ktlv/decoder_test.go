@@ -0,0 +1,121 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	list := List{
+		&Elem{1, Uint8, uint8(1)},
+		&Elem{2, List_of_Uint8, []uint8{1, 2, 3}},
+		&Elem{3, String, "abc"},
+		&Elem{4, List_of_Uint8, []uint8{4, 5}},
+		&Elem{5, 200, Raw{6, 7}},
+		&Elem{6, Object, List{&Elem{1, List_of_Uint8, []uint8{8, 9}}}},
+		&Elem{7, List_of_Uint8, []uint8{10, 11, 12, 13, 14, 15, 16}},
+	}
+	encoded, err := list.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewDecoder(iotest.OneByteReader(bytes.NewReader(encoded)))
+	decoded := List{}
+	for {
+		elem, err := decoder.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+		decoded = append(decoded, elem)
+	}
+	if len(decoded) != len(list) {
+		t.Fatalf("expected %v but %v found", list, decoded)
+	}
+	for i, elem := range list {
+		if !elem.Equals(decoded[i]) {
+			t.Errorf("elems differ: %v and %v", elem, decoded[i])
+		}
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	encoded, err := List{&Elem{1, String, "abc"}}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testset := []struct {
+		Len    int
+		Expect error
+	}{
+		{0, io.EOF},
+		{1, io.ErrUnexpectedEOF},
+		{4, io.ErrUnexpectedEOF},
+		{5, io.ErrUnexpectedEOF},
+		{7, io.ErrUnexpectedEOF},
+	}
+	for n, test := range testset {
+		decoder := NewDecoder(bytes.NewReader(encoded[:test.Len]))
+		if _, err := decoder.Next(); err != test.Expect {
+			t.Errorf("#%d> expected %v but %v found",
+				n, test.Expect, err)
+		}
+	}
+}
+
+// Endless stream repeating the same bytes.
+type repeatReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.b[r.pos]
+		r.pos = (r.pos + 1) % len(r.b)
+	}
+	return len(p), nil
+}
+
+func TestDecoderLimits(t *testing.T) {
+	// chunk which is never the last one
+	chunk := append([]byte{0, 1, Chunk, 0xff, 0xff, String, 0},
+		bytes.Repeat([]byte{'a'}, maxChunkLen)...)
+	opts := DecodeOptions{MaxBytes: 1 << 20}
+	decoder := opts.NewDecoder(&repeatReader{b: chunk})
+	_, err := decoder.Next()
+	var derr *DecodeError
+	if !errors.As(err, &derr) || !errors.Is(err, MessageTooLong) ||
+		derr.Offset != 0 || derr.Key != 1 {
+		t.Errorf("expected MessageTooLong but %v found", err)
+	}
+	encoded, err := List{
+		&Elem{1, String, "abc"},
+		&Elem{2, String, "abcd"},
+		&Elem{3, Uint8, uint8(1)},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = DecodeOptions{MaxBytes: 8, MaxStringLen: 3}
+	decoder = opts.NewDecoder(bytes.NewReader(encoded))
+	if elem, err := decoder.Next(); err != nil || elem.Key != 1 {
+		t.Errorf("unexpected result: %v (%v)", elem, err)
+	}
+	if _, err := decoder.Next(); !errors.Is(err, MessageTooLong) {
+		t.Errorf("expected MessageTooLong but %v found", err)
+	}
+	opts = DecodeOptions{MaxStringLen: 3}
+	decoder = opts.NewDecoder(bytes.NewReader(encoded))
+	if _, err := decoder.Next(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := decoder.Next(); !errors.As(err, &derr) ||
+		!errors.Is(err, StringTooLong) || derr.Offset != 8 {
+		t.Errorf("expected StringTooLong but %v found", err)
+	}
+}
@@ -1,41 +1,148 @@
 package ktlv
 
 import (
-	"bytes"
 	"fmt"
+	"iter"
+	"sort"
 )
 
 type Dict map[uint16]*Elem
 
 // Encode dictionary with input data to byte buffer.
+// Elements are encoded in ascending key order, elements of
+// nested objects too (objects given as List are encoded as
+// List.Dict() is, but duplicate keys in them fail), so the
+// result is canonical (see IsCanonical).
 func (d Dict) Encode() ([]byte, error) {
-	buffer := &bytes.Buffer{}
-	for _, elem := range d {
-		encoded, err := elem.Encode()
+	return d.AppendTo(nil)
+}
+
+// Encode dictionary to byte buffer as Encode does, but split
+// values longer than 65535 bytes. See Elem.EncodeChunked.
+func (d Dict) EncodeChunked() ([]byte, error) {
+	return d.appendTo(nil, appendElemChunked)
+}
+
+// Append encoded elements to dst in ascending key order.
+// See AppendElem. On error returns dst unchanged.
+func (d Dict) AppendTo(dst []byte) ([]byte, error) {
+	return d.appendTo(dst, AppendElem)
+}
+
+// Append all elements to dst with given element encoder.
+// Nested objects are encoded as dictionaries, so the result is
+// canonical. Fails when map key differs from element key or
+// nested List has several elements with the same key.
+func (d Dict) appendTo(dst []byte, appendElem func([]byte, *Elem) ([]byte, error)) ([]byte, error) {
+	res := dst
+	var err error
+	for key, elem := range d.Sorted() {
+		if elem.Key != key {
+			return dst, fmt.Errorf("encode key#%d: element key#%d"+
+				" is stored under another key", key, elem.Key)
+		}
+		if elem, err = canonicalElem(elem); err != nil {
+			return dst, err
+		}
+		if res, err = appendElem(res, elem); err != nil {
+			return dst, err
+		}
+	}
+	return res, nil
+}
+
+// Return element with nested objects converted to dictionaries.
+// Elements without List values are returned as is.
+func canonicalElem(elem *Elem) (*Elem, error) {
+	switch v := elem.Value.(type) {
+	case List:
+		object, err := canonicalObject(elem.Key, v)
 		if err != nil {
 			return nil, err
 		}
-		if _, err := buffer.Write(encoded); err != nil {
-			return nil, err
+		return &Elem{elem.Key, elem.FType, object}, nil
+	case []List:
+		objects := make([]Dict, len(v))
+		for i, o := range v {
+			var err error
+			if objects[i], err = canonicalObject(elem.Key, o); err != nil {
+				return nil, err
+			}
 		}
+		return &Elem{elem.Key, elem.FType, objects}, nil
 	}
-	return buffer.Bytes(), nil
+	return elem, nil
+}
+
+// Convert nested object to dictionary. Fails on duplicate keys,
+// as only one of the elements would be encoded.
+func canonicalObject(key uint16, list List) (Dict, error) {
+	res := make(Dict, len(list))
+	for _, elem := range list {
+		if _, ok := res[elem.Key]; ok {
+			return nil, fmt.Errorf("encode key#%d: duplicate key#%d"+
+				" in nested object", key, elem.Key)
+		}
+		res[elem.Key] = elem
+	}
+	return res, nil
 }
 
 // Decode data from byte buffer to dictionary.
+// Elements of unknown field types are kept undecoded with
+// values of Raw type, so they will be encoded back unchanged.
 // On error returns non nil value with all successfully decoded
 // elements.
+// See DecodeOptions for decoding of untrusted input.
 func DecodeDict(bytes []byte) (Dict, error) {
-	res := Dict{}
-	for 0 < len(bytes) {
-		elem, tail, err := scan(bytes)
-		if err != nil {
-			return res, err
+	return noLimits.DecodeDict(bytes)
+}
+
+// Decode data from byte buffer to dictionary as DecodeDict does,
+// but return elements of unknown field types as a separate list.
+// Values of such elements are of Raw type.
+func DecodeDictWithUnknown(bytes []byte) (res Dict, unknown List, err error) {
+	res = Dict{}
+	c := NewCursor(bytes)
+	for key, elem := range c.elems() {
+		if _, ok := elem.Value.(Raw); ok {
+			unknown = append(unknown, elem)
+		} else {
+			res[key] = elem
 		}
-		res[elem.Key] = elem
-		bytes = tail
 	}
-	return res, nil
+	return res, unknown, c.Err()
+}
+
+// Iterate over dictionary elements in ascending key order.
+func (d Dict) Sorted() iter.Seq2[uint16, *Elem] {
+	return func(yield func(uint16, *Elem) bool) {
+		for _, key := range d.Keys() {
+			if !yield(key, d[key]) {
+				return
+			}
+		}
+	}
+}
+
+// Convert dict of elements to list of elements in ascending
+// key order.
+func (d Dict) List() List {
+	list := make(List, 0, len(d))
+	for _, elem := range d.Sorted() {
+		list = append(list, elem)
+	}
+	return list
+}
+
+// Return dictionary keys sorted in ascending order.
+func (d Dict) Keys() []uint16 {
+	keys := make([]uint16, 0, len(d))
+	for key := range d {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
 }
 
 // Add new element to data dictionary.
@@ -52,7 +159,7 @@ func (d Dict) Get(key uint16) (ftype uint8, v interface{}, ok bool) {
 
 func (d Dict) String() string {
 	s := ""
-	for k, e := range d {
+	for k, e := range d.Sorted() {
 		if s != "" {
 			s += ","
 		}
@@ -1,7 +1,7 @@
 package ktlv
 
 import (
-	"encoding/binary"
+	"bytes"
 	"fmt"
 	"io"
 )
@@ -12,75 +12,50 @@ type Elem struct {
 	Value interface{}
 }
 
+// Undecoded body of element of unknown field type.
+// Shares memory with decoded byte buffer (as List_of_Uint8
+// values do, see DecodeOptions.ZeroCopy), so copy it before the
+// buffer is modified or reused. Decoder returns copies. Elements
+// of unknown field types with Raw values are encoded back
+// unchanged.
+type Raw []byte
+
 // Encode data element to bytes.
 func (e *Elem) Encode() ([]byte, error) {
-	body, err := e.encodeValue()
+	return AppendElem(nil, e)
+}
+
+// Append encoded element to dst. Header and body are written
+// directly to dst, so no memory is allocated when dst has enough
+// capacity (except for nested objects). On error returns dst
+// unchanged.
+func AppendElem(dst []byte, e *Elem) ([]byte, error) {
+	res, mark := BeginElem(dst, e.Key, e.FType)
+	res, err := appendValue(res, e.FType, e.Value)
+	if err == nil {
+		res, err = EndElem(res, mark)
+	}
 	if err != nil {
-		return nil, err
+		return dst, fmt.Errorf("encode key#%d: %w", e.Key, err)
 	}
-	res := make([]byte, len(body)+5)
-	copy(res[5:], body)
-	binary.BigEndian.PutUint16(res[0:2], uint16(e.Key))
-	res[2] = uint8(e.FType)
-	binary.BigEndian.PutUint16(res[3:5], uint16(len(body)))
 	return res, nil
 }
 
 // Search and decode one element with given key in octet stream.
+// See DecodeOptions for decoding of untrusted input.
 func DecodeElem(b []byte, key uint16) (*Elem, error) {
-	for {
-		bLen := len(b)
-		if bLen < 5 {
-			break
-		}
-		fKey := binary.BigEndian.Uint16(b)
-		fType := b[2]
-		fLen := int(binary.BigEndian.Uint16(b[3:]))
-		if tLen := bLen - 5; tLen < fLen {
-			return nil, fmt.Errorf("broken "+
-				"elem key#%d type=%d. expected body"+
-				" len is %d but %d found",
-				fKey, fType, fLen, tLen)
-		}
-		if fKey == key {
-			value, err := decodeValue(fType, b[5:5+fLen])
-			if err != nil {
-				return nil, err
-			}
-			return &Elem{fKey, fType, value}, nil
-		}
-		b = b[5+fLen:]
-	}
-	return nil, ElementNotFound
+	return noLimits.DecodeElem(b, key)
 }
 
-func (e *Elem) WriteTo(writer io.Writer) (n int, err error) {
-	encodedValue, err := e.encodeValue()
+// Write encoded element to writer with a single Write call.
+// Implements io.WriterTo.
+func (e *Elem) WriteTo(writer io.Writer) (int64, error) {
+	encoded, err := e.Encode()
 	if err != nil {
 		return 0, err
 	}
-	header := make([]byte, 5)
-	binary.BigEndian.PutUint16(header, uint16(e.Key))
-	header[2] = uint8(e.FType)
-	binary.BigEndian.PutUint16(header[3:], uint16(len(encodedValue)))
-	n1, err := writer.Write(header)
-	if err != nil {
-		return n1, err
-	}
-	n2, err := writer.Write(encodedValue)
-	if err != nil {
-		return n2, err
-	}
-	return n1 + n2, nil
-}
-
-// Encode element value to bytes.
-func (e *Elem) encodeValue() ([]byte, error) {
-	encoded, err := encodeValue(e.FType, e.Value)
-	if err != nil {
-		return nil, fmt.Errorf("encode key#%d: %s", e.Key, err)
-	}
-	return encoded, nil
+	n, err := writer.Write(encoded)
+	return int64(n), err
 }
 
 // Check if elements are equal or not.
@@ -89,6 +64,10 @@ func (e1 *Elem) Equals(e2 *Elem) bool {
 	if e1.Key != e2.Key || e1.FType != e2.FType {
 		return false
 	}
+	if v1, ok := e1.Value.(Raw); ok {
+		v2, ok := e2.Value.(Raw)
+		return ok && bytes.Equal(v1, v2)
+	}
 	switch e1.FType {
 	case Bitmap:
 		v1, _ := e1.Value.([]bool)
@@ -233,8 +212,75 @@ func (e1 *Elem) Equals(e2 *Elem) bool {
 				return false
 			}
 		}
+	case Object:
+		return objectsEqual(e1.Value, e2.Value)
+	case List_of_Object:
+		v1, v2 := objectItems(e1.Value), objectItems(e2.Value)
+		if len(v1) != len(v2) {
+			return false
+		}
+		for i := 0; i < len(v1); i++ {
+			if !objectsEqual(v1[i], v2[i]) {
+				return false
+			}
+		}
 	default:
 		return e1.Value == e2.Value
 	}
 	return true
 }
+
+// Check if nested objects (List or Dict values) are equal.
+// List and Dict are compared as dictionaries.
+func objectsEqual(o1, o2 interface{}) bool {
+	l1, ok1 := o1.(List)
+	l2, ok2 := o2.(List)
+	if ok1 && ok2 {
+		if len(l1) != len(l2) {
+			return false
+		}
+		for i := 0; i < len(l1); i++ {
+			if !l1[i].Equals(l2[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	d1, d2 := toDict(o1), toDict(o2)
+	if len(d1) != len(d2) {
+		return false
+	}
+	for k, e1 := range d1 {
+		if e2, ok := d2[k]; !ok || !e1.Equals(e2) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return items of List_of_Object value ([]List or []Dict).
+func objectItems(v interface{}) []interface{} {
+	var res []interface{}
+	switch v := v.(type) {
+	case []List:
+		for _, o := range v {
+			res = append(res, o)
+		}
+	case []Dict:
+		for _, o := range v {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// Convert nested object to Dict.
+func toDict(o interface{}) Dict {
+	switch v := o.(type) {
+	case List:
+		return v.Dict()
+	case Dict:
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package ktlv
+
+import (
+	"fmt"
+	"io"
+)
+
+// Size of Encoder internal buffer. Buffer is flushed to the
+// underlying writer as soon as it grows beyond this size.
+const encoderBufferSize = 4096
+
+// Key-value pair to be encoded with Encoder.EncodeKV.
+type KV struct {
+	Key   uint16
+	Value interface{}
+}
+
+// Encoder encodes elements to an output stream.
+// Encoded elements are buffered, so Flush must be called after
+// the last element is encoded. As with bufio.Writer, after the
+// first write error all subsequent calls return the same error.
+type Encoder struct {
+	// Field types of elements encoded with EncodeKV.
+	FTypes map[uint16]uint8
+
+	w       io.Writer
+	buf     []byte
+	written int64
+	err     error
+}
+
+// Create new encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, buf: make([]byte, 0, encoderBufferSize)}
+}
+
+// Encode elements to the stream.
+func (e *Encoder) Encode(elements ...*Elem) error {
+	for _, elem := range elements {
+		if e.err != nil {
+			return e.err
+		}
+		var err error
+		if e.buf, err = AppendElem(e.buf, elem); err != nil {
+			return err
+		}
+		if encoderBufferSize <= len(e.buf) {
+			if err := e.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Encode key-value pairs to the stream. Field types are taken
+// from FTypes map.
+func (e *Encoder) EncodeKV(elements ...KV) error {
+	for _, kv := range elements {
+		ftype, ok := e.FTypes[kv.Key]
+		if !ok {
+			return fmt.Errorf("encode key#%d: no field type", kv.Key)
+		}
+		if err := e.Encode(&Elem{kv.Key, ftype, kv.Value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write all buffered data to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(e.buf) == 0 {
+		return nil
+	}
+	n, err := e.w.Write(e.buf)
+	e.written += int64(n)
+	if err == nil && n < len(e.buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		e.buf = e.buf[:copy(e.buf, e.buf[n:])]
+		e.err = err
+		return err
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// Number of bytes buffered but not written yet.
+func (e *Encoder) Buffered() int {
+	return len(e.buf)
+}
+
+// Number of bytes written to the underlying writer.
+func (e *Encoder) Written() int64 {
+	return e.written
+}
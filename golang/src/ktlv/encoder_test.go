@@ -0,0 +1,107 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	list := List{
+		&Elem{1, Uint8, uint8(1)},
+		&Elem{2, String, "abc"},
+		&Elem{300, List_of_Uint16, []uint16{1, 2}},
+	}
+	expect, err := list.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &bytes.Buffer{}
+	encoder := NewEncoder(buffer)
+	encoder.FTypes = map[uint16]uint8{2: String, 300: List_of_Uint16}
+	if err := encoder.Encode(list[0]); err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if err := encoder.EncodeKV(KV{2, "abc"},
+		KV{300, []uint16{1, 2}}); err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if err := encoder.EncodeKV(KV{3, "abc"}); err == nil {
+		t.Errorf("expected error for unknown key")
+	}
+	if err := encoder.EncodeKV(KV{2, 1}); err == nil {
+		t.Errorf("expected error for bad value")
+	}
+	if buffer.Len() != 0 || encoder.Buffered() != len(expect) {
+		t.Fatalf("unexpected buffering: %d/%d",
+			buffer.Len(), encoder.Buffered())
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("flush: %s", err)
+	}
+	if !bytes.Equal(buffer.Bytes(), expect) {
+		t.Fatalf("expected %v but %v found", expect, buffer.Bytes())
+	}
+	if encoder.Written() != int64(len(expect)) || encoder.Buffered() != 0 {
+		t.Errorf("unexpected byte counters: %d/%d",
+			encoder.Written(), encoder.Buffered())
+	}
+}
+
+func TestEncoderAutoFlush(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	encoder := NewEncoder(buffer)
+	for i := 0; i < 1000; i++ {
+		if err := encoder.Encode(&Elem{uint16(i), Uint32, uint32(i)}); err != nil {
+			t.Fatalf("encode: %s", err)
+		}
+	}
+	if buffer.Len() == 0 || encoderBufferSize < encoder.Buffered() {
+		t.Errorf("unexpected buffering: %d/%d",
+			buffer.Len(), encoder.Buffered())
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("flush: %s", err)
+	}
+	if buffer.Len() != 9000 || encoder.Written() != 9000 {
+		t.Errorf("unexpected byte counters: %d/%d",
+			buffer.Len(), encoder.Written())
+	}
+}
+
+// Writer which accepts limited number of bytes.
+type limitedWriter struct {
+	limit int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if len(b) <= w.limit {
+		w.limit -= len(b)
+		return len(b), nil
+	}
+	n := w.limit
+	w.limit = 0
+	return n, errors.New("no space left")
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	encoder := NewEncoder(&limitedWriter{7})
+	if err := encoder.Encode(&Elem{1, Uint32, uint32(1)},
+		&Elem{2, Uint32, uint32(2)}); err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if err := encoder.Flush(); err == nil {
+		t.Fatalf("expected flush error")
+	}
+	if encoder.Written() != 7 || encoder.Buffered() != 11 {
+		t.Errorf("unexpected byte counters: %d/%d",
+			encoder.Written(), encoder.Buffered())
+	}
+	if err := encoder.Encode(&Elem{3, Uint8, uint8(3)}); err == nil {
+		t.Errorf("expected sticky error")
+	}
+	n, err := (&Elem{1, Uint32, uint32(1)}).WriteTo(&limitedWriter{7})
+	if err == nil || n != 7 {
+		t.Errorf("unexpected WriteTo result: %d (%v)", n, err)
+	}
+}
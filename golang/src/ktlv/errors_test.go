@@ -0,0 +1,82 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDecodeError(t *testing.T) {
+	testset := []struct {
+		input  []byte
+		err    error
+		offset int
+		key    uint16
+		ftype  uint8
+	}{
+		{[]byte{0, 1, Uint8, 0, 1, 1, 0, 2}, Truncated, 6, 0, 0},
+		{[]byte{0, 1, Uint8, 0, 1, 1, 0, 2, String, 0, 3, 1}, Truncated, 6, 2, String},
+		{[]byte{0, 1, Uint8, 0, 1, 1, 0, 2, Uint16, 0, 1, 1}, BadLength, 6, 2, Uint16},
+		{[]byte{0, 1, Bitmap, 0, 1, 9}, Malformed, 0, 1, Bitmap},
+		{[]byte{0, 1, Chunk, 0, 3, Uint8, 0, 1}, Truncated, 0, 1, Chunk},
+		{[]byte{0, 1, Chunk, 0, 3, Uint8, 2, 1}, Malformed, 0, 1, Chunk},
+		// nested element #2 of Object element #1
+		{[]byte{0, 1, Object, 0, 11,
+			0, 1, Uint8, 0, 1, 1,
+			0, 2, Uint16, 0, 0}, BadLength, 11, 2, Uint16},
+		// nested element #3 of the second object of element #1
+		{[]byte{0, 1, List_of_Object, 0, 10,
+			0, 0,
+			0, 6, 0, 3, Int8, 0, 2, 0}, Truncated, 9, 3, Int8},
+		{[]byte{0, 1, List_of_Object, 0, 1, 0}, BadLength, 0, 1, List_of_Object},
+	}
+	for n, test := range testset {
+		_, err := DecodeList(test.input)
+		var de *DecodeError
+		if !errors.Is(err, test.err) || !errors.As(err, &de) {
+			t.Errorf("#%d> expected %v but %v found", n, test.err, err)
+			continue
+		}
+		if de.Offset != test.offset || de.Key != test.key || de.FType != test.ftype {
+			t.Errorf("#%d> unexpected error: %#v", n, de)
+		}
+		if _, err := DecodeDict(test.input); !errors.Is(err, test.err) {
+			t.Errorf("#%d> dict: expected %v but %v found", n, test.err, err)
+		}
+		if test.err == Truncated {
+			continue
+		}
+		decoder := NewDecoder(bytes.NewReader(test.input))
+		for err = nil; err == nil; _, err = decoder.Next() {
+		}
+		if !errors.As(err, &de) || de.Offset != test.offset {
+			t.Errorf("#%d> decoder: expected %v but %v found", n, test.err, err)
+		}
+	}
+	if _, err := Search([]byte{0, 1, Uint8, 0, 1, 1}, 2, 5); err != io.EOF {
+		t.Errorf("expected EOF but %v found", err)
+	}
+	if _, err := DecodeElem([]byte{0, 1, Uint8, 0, 1, 1, 0, 2, Uint8, 0, 2, 1, 1}, 2); !errors.Is(err, BadLength) {
+		t.Errorf("expected BadLength but %v found", err)
+	} else if de := err.(*DecodeError); de.Offset != 6 {
+		t.Errorf("unexpected offset: %d", de.Offset)
+	}
+	if _, err := (&Elem{1, 200, "a"}).Encode(); !errors.Is(err, UnknownFType) {
+		t.Errorf("expected UnknownFType but %v found", err)
+	}
+}
+
+func TestDecoderErrorOffset(t *testing.T) {
+	input := []byte{0, 1, Uint8, 0, 1, 1, 0, 2, Int16, 0, 1, 1}
+	decoder := NewDecoder(bytes.NewReader(input))
+	if _, err := decoder.Next(); err != nil {
+		t.Fatalf("next: %s", err)
+	}
+	_, err := decoder.Next()
+	var de *DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, BadLength) ||
+		de.Offset != 6 || de.Key != 2 || de.FType != Int16 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
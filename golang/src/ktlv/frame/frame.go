@@ -0,0 +1,215 @@
+// Package frame implements length-prefixed framing of KTLV
+// messages over byte streams such as TCP or Unix sockets.
+//
+// Each frame consists of 4 bytes of payload length (unsigned,
+// big-endian) followed by the payload itself, which is normally
+// an encoded ktlv.List or ktlv.Dict.
+package frame
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"ktlv"
+)
+
+// Length of the frame header.
+const HeaderLen = 4
+
+// Maximum frame payload size used unless configured otherwise.
+const DefaultMaxSize = 1 << 20
+
+// Maximum nesting depth of objects decoded by FrameReader unless
+// configured otherwise.
+const DefaultMaxDepth = 64
+
+// Decoding limits used by FrameReader unless configured otherwise.
+var defaultDecodeOptions = &ktlv.DecodeOptions{MaxDepth: DefaultMaxDepth}
+
+// Max capacity of the frame buffer kept by FrameWriter between
+// writes. Larger buffers are released after the write.
+const maxKeptBuf = 64 << 10
+
+// Returned when frame payload exceeds the maximum frame size.
+var FrameTooLarge = errors.New("frame too large")
+
+// Implemented by net.Conn.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// Implemented by net.Conn.
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// FrameWriter writes KTLV messages as length-prefixed frames.
+// Each frame is written with a single Write call, so frames
+// of concurrent writers sharing one connection never interleave
+// as long as the connection serializes writes (net.Conn does).
+type FrameWriter struct {
+	// Maximum frame payload size. Zero means DefaultMaxSize.
+	MaxSize int
+	// If positive and the writer implements SetWriteDeadline
+	// (like net.Conn does), each frame must be written within
+	// this time.
+	Timeout time.Duration
+
+	w   io.Writer
+	buf []byte
+}
+
+// Create new frame writer writing to w.
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: w}
+}
+
+// Write payload as one frame.
+func (fw *FrameWriter) WriteFrame(payload []byte) error {
+	if err := checkSize(len(payload), fw.MaxSize); err != nil {
+		return err
+	}
+	fw.buf = append(fw.buf[:0], 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(fw.buf, uint32(len(payload)))
+	fw.buf = append(fw.buf, payload...)
+	err := fw.write()
+	if maxKeptBuf < cap(fw.buf) {
+		// don't hold a large payload for the writer life time
+		fw.buf = nil
+	} else {
+		fw.buf = fw.buf[:0]
+	}
+	return err
+}
+
+// Encode list of elements and write it as one frame.
+// Long values are chunked (see ktlv.List.EncodeChunked).
+func (fw *FrameWriter) WriteList(list ktlv.List) error {
+	payload, err := list.EncodeChunked()
+	if err != nil {
+		return err
+	}
+	return fw.WriteFrame(payload)
+}
+
+// Encode dictionary of elements and write it as one frame.
+// Long values are chunked (see ktlv.Dict.EncodeChunked).
+func (fw *FrameWriter) WriteDict(dict ktlv.Dict) error {
+	payload, err := dict.EncodeChunked()
+	if err != nil {
+		return err
+	}
+	return fw.WriteFrame(payload)
+}
+
+// Write buffered frame to the underlying writer.
+func (fw *FrameWriter) write() error {
+	if d, ok := fw.w.(writeDeadliner); ok && 0 < fw.Timeout {
+		if err := d.SetWriteDeadline(time.Now().Add(fw.Timeout)); err != nil {
+			return err
+		}
+	}
+	n, err := fw.w.Write(fw.buf)
+	if err == nil && n < len(fw.buf) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// FrameReader reads KTLV messages from length-prefixed frames.
+// After any error except io.EOF the stream position is undefined,
+// so the underlying connection should be closed.
+type FrameReader struct {
+	// Maximum frame payload size. Zero means DefaultMaxSize.
+	// Larger frames are rejected with FrameTooLarge error
+	// before the payload is read.
+	MaxSize int
+	// If positive and the reader implements SetReadDeadline
+	// (like net.Conn does), each frame must be read within
+	// this time. The deadline is set before the header is read,
+	// so it limits idle time between frames too.
+	Timeout time.Duration
+	// Limits for decoding frames by ReadList and ReadDict. Nil
+	// means DefaultMaxDepth limit only, as payload length is
+	// already bounded by MaxSize.
+	DecodeOptions *ktlv.DecodeOptions
+
+	r      io.Reader
+	header [HeaderLen]byte
+}
+
+// Create new frame reader reading from r.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r}
+}
+
+// Read next frame payload. Returns io.EOF when stream ends at
+// frame boundary and io.ErrUnexpectedEOF when stream ends in
+// the middle of a frame.
+func (fr *FrameReader) ReadFrame() ([]byte, error) {
+	if d, ok := fr.r.(readDeadliner); ok && 0 < fr.Timeout {
+		if err := d.SetReadDeadline(time.Now().Add(fr.Timeout)); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := io.ReadFull(fr.r, fr.header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(fr.header[:])
+	if uint64(size) > uint64(maxSize(fr.MaxSize)) {
+		return nil, fmt.Errorf("read frame: %w: %d bytes", FrameTooLarge, size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(fr.r, payload); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Read next frame and decode it as list of elements.
+func (fr *FrameReader) ReadList() (ktlv.List, error) {
+	payload, err := fr.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return fr.decodeOptions().DecodeList(payload)
+}
+
+// Read next frame and decode it as dictionary of elements.
+func (fr *FrameReader) ReadDict() (ktlv.Dict, error) {
+	payload, err := fr.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return fr.decodeOptions().DecodeDict(payload)
+}
+
+// Return effective decoding limits.
+func (fr *FrameReader) decodeOptions() *ktlv.DecodeOptions {
+	if fr.DecodeOptions == nil {
+		return defaultDecodeOptions
+	}
+	return fr.DecodeOptions
+}
+
+// Return effective maximum frame size.
+func maxSize(limit int) int {
+	if limit <= 0 {
+		return DefaultMaxSize
+	}
+	return limit
+}
+
+// Check payload size against maximum frame size.
+func checkSize(size, limit int) error {
+	if maxSize(limit) < size || 0xffffffff < uint64(size) {
+		return fmt.Errorf("write frame: %w: %d bytes", FrameTooLarge, size)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package frame
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"ktlv"
+)
+
+func TestFrames(t *testing.T) {
+	list := ktlv.List{
+		&ktlv.Elem{Key: 1, FType: ktlv.String, Value: "hello"},
+		&ktlv.Elem{Key: 2, FType: ktlv.String, Value: strings.Repeat("a", 0x20000)},
+	}
+	dict := ktlv.Dict{}
+	dict.Add(3, ktlv.Uint32, uint32(3))
+	buf := &bytes.Buffer{}
+	fw := NewFrameWriter(buf)
+	if err := fw.WriteList(list); err != nil {
+		t.Fatalf("write list: %s", err)
+	}
+	if err := fw.WriteDict(dict); err != nil {
+		t.Fatalf("write dict: %s", err)
+	}
+	if err := fw.WriteFrame(nil); err != nil {
+		t.Fatalf("write frame: %s", err)
+	}
+	fr := NewFrameReader(buf)
+	list1, err := fr.ReadList()
+	if err != nil {
+		t.Fatalf("read list: %s", err)
+	}
+	if len(list1) != len(list) {
+		t.Fatalf("expected %d elements but %d found", len(list), len(list1))
+	}
+	for i, elem := range list {
+		if !elem.Equals(list1[i]) {
+			t.Errorf("elems #%d differ", i)
+		}
+	}
+	dict1, err := fr.ReadDict()
+	if err != nil {
+		t.Fatalf("read dict: %s", err)
+	}
+	if len(dict1) != 1 || !dict1[3].Equals(dict[3]) {
+		t.Errorf("unexpected dict: %v", dict1)
+	}
+	if payload, err := fr.ReadFrame(); err != nil || len(payload) != 0 {
+		t.Errorf("expected empty frame but %v, %v found", payload, err)
+	}
+	if _, err := fr.ReadFrame(); err != io.EOF {
+		t.Errorf("expected EOF but %v found", err)
+	}
+}
+
+func TestFrameWriterBuf(t *testing.T) {
+	fw := NewFrameWriter(io.Discard)
+	if err := fw.WriteFrame([]byte("small")); err != nil {
+		t.Fatalf("write frame: %s", err)
+	}
+	if cap(fw.buf) == 0 {
+		t.Errorf("expected buffer of small frame to be kept")
+	}
+	if err := fw.WriteFrame(make([]byte, maxKeptBuf+1)); err != nil {
+		t.Fatalf("write frame: %s", err)
+	}
+	if maxKeptBuf < cap(fw.buf) {
+		t.Errorf("expected buffer of large frame to be released but"+
+			" %d bytes kept", cap(fw.buf))
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	fw := NewFrameWriter(&bytes.Buffer{})
+	fw.MaxSize = 3
+	if err := fw.WriteFrame([]byte{1, 2, 3, 4}); !errors.Is(err, FrameTooLarge) {
+		t.Errorf("expected FrameTooLarge but %v found", err)
+	}
+	testset := []struct {
+		input   []byte
+		maxSize int
+		err     error
+	}{
+		{[]byte{0, 0}, 0, io.ErrUnexpectedEOF},
+		{[]byte{0, 0, 0, 2, 1}, 0, io.ErrUnexpectedEOF},
+		{[]byte{0, 0, 0, 4, 1, 2, 3, 4}, 3, FrameTooLarge},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, 0, FrameTooLarge},
+	}
+	for n, test := range testset {
+		fr := NewFrameReader(bytes.NewReader(test.input))
+		fr.MaxSize = test.maxSize
+		if _, err := fr.ReadFrame(); !errors.Is(err, test.err) {
+			t.Errorf("#%d> expected %v but %v found", n, test.err, err)
+		}
+	}
+}
+
+func TestFrameConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	fr := NewFrameReader(server)
+	fr.Timeout = 50 * time.Millisecond
+	if _, err := fr.ReadFrame(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected deadline error but %v found", err)
+	}
+	list := ktlv.List{&ktlv.Elem{Key: 1, FType: ktlv.Uint8, Value: uint8(1)}}
+	go func() {
+		fw := NewFrameWriter(client)
+		fw.Timeout = time.Second
+		fw.WriteList(list)
+	}()
+	fr.Timeout = time.Second
+	list1, err := fr.ReadList()
+	if err != nil {
+		t.Fatalf("read list: %s", err)
+	}
+	if len(list1) != 1 || !list1[0].Equals(list[0]) {
+		t.Errorf("unexpected list: %v", list1)
+	}
+}
+
+func TestFrameLimits(t *testing.T) {
+	list := ktlv.List{&ktlv.Elem{Key: 1, FType: ktlv.Uint8, Value: uint8(1)}}
+	for i := 0; i < 2*DefaultMaxDepth; i++ {
+		list = ktlv.List{&ktlv.Elem{Key: 1, FType: ktlv.Object, Value: list}}
+	}
+	buf := &bytes.Buffer{}
+	fw := NewFrameWriter(buf)
+	for i := 0; i < 3; i++ {
+		if err := fw.WriteList(list); err != nil {
+			t.Fatalf("write list: %s", err)
+		}
+	}
+	fr := NewFrameReader(buf)
+	if _, err := fr.ReadList(); !errors.Is(err, ktlv.NestingTooDeep) {
+		t.Errorf("expected nesting error but %v found", err)
+	}
+	if _, err := fr.ReadDict(); !errors.Is(err, ktlv.NestingTooDeep) {
+		t.Errorf("expected nesting error but %v found", err)
+	}
+	fr.DecodeOptions = &ktlv.DecodeOptions{}
+	if _, err := fr.ReadList(); err != nil {
+		t.Errorf("read list: %s", err)
+	}
+}
@@ -0,0 +1,105 @@
+package ktlv
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Add encoded messages of all field types to the fuzz corpus.
+func addSeeds(f *testing.F) {
+	seeds := []List{
+		{},
+		{&Elem{1, Bool, true}, &Elem{2, Uint8, Max_Uint8},
+			&Elem{3, Uint16, Max_Uint16}, &Elem{4, Uint24, Max_Uint24},
+			&Elem{5, Uint32, Max_Uint32}, &Elem{6, Uint64, Max_Uint64},
+			&Elem{7, Double, 3.1415927}, &Elem{8, String, "abc"},
+			&Elem{9, Bitmap, []bool{true, false, true}},
+			&Elem{10, Int8, Min_Int8}, &Elem{11, Int16, Min_Int16},
+			&Elem{12, Int24, Min_Int24}, &Elem{13, Int32, Min_Int32},
+			&Elem{14, Int64, Min_Int64}},
+		{&Elem{1, List_of_String, []string{"a", ""}},
+			&Elem{2, List_of_Uint8, []uint8{1, 2}},
+			&Elem{3, List_of_Uint16, []uint16{1, 2}},
+			&Elem{4, List_of_Uint24, []uint32{1, 2}},
+			&Elem{5, List_of_Uint32, []uint32{1, 2}},
+			&Elem{6, List_of_Uint64, []uint64{1, 2}},
+			&Elem{7, List_of_Double, []float64{1, 2}},
+			&Elem{8, List_of_Int8, []int8{-1, 2}},
+			&Elem{9, List_of_Int16, []int16{-1, 2}},
+			&Elem{10, List_of_Int24, []int32{-1, 2}},
+			&Elem{11, List_of_Int32, []int32{-1, 2}},
+			&Elem{12, List_of_Int64, []int64{-1, 2}}},
+		{&Elem{1, Object, List{&Elem{1, Uint8, uint8(1)}}},
+			&Elem{2, List_of_Object, []List{{}, {&Elem{1, Bool, false}}}},
+			&Elem{3, 200, Raw{1, 2, 3}}},
+	}
+	for _, list := range seeds {
+		encoded, err := list.EncodeChunked()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(encoded)
+	}
+	// short chunks keep the corpus small
+	f.Add([]byte{0, 1, Chunk, 0, 3, String, 0, 'a',
+		0, 1, Chunk, 0, 3, String, 1, 'b'})
+	f.Add([]byte{0, 1, Bitmap, 0, 1, 9})
+}
+
+func FuzzDecodeList(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		list, err := DecodeList(b)
+		if err != nil {
+			return
+		}
+		// all decoded elements must be encodable again
+		encoded, err := list.EncodeChunked()
+		if err != nil {
+			t.Fatalf("reencode: %s", err)
+		}
+		if _, err := DecodeList(encoded); err != nil {
+			t.Fatalf("decode reencoded: %s", err)
+		}
+		// stream decoder must agree with DecodeList
+		decoder := NewDecoder(bytes.NewReader(b))
+		for i := range list {
+			if _, err := decoder.Next(); err != nil {
+				t.Fatalf("next #%d: %s", i, err)
+			}
+		}
+	})
+}
+
+func FuzzDecodeDict(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		dict, err := DecodeDict(b)
+		if err != nil {
+			return
+		}
+		if _, err := dict.EncodeChunked(); err != nil {
+			t.Fatalf("reencode: %s", err)
+		}
+		IsCanonical(b)
+	})
+}
+
+func FuzzDecodeElem(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, key := range []uint16{0, 1, 2, 3, 8, 9} {
+			DecodeElem(b, key)
+		}
+	})
+}
+
+func FuzzSearch(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, key := range []uint16{0, 1, 2, 3, 8, 9} {
+			Search(b, key, 16)
+			SearchUint64(b, key, 16)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package ktlv
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Go types of element values (see Elem). Values of 24-bit field
+// types have the same Go types as 32-bit ones. Object values are
+// List or Dict, List_of_Object values are []List or []Dict.
+type Value interface {
+	bool | uint8 | uint16 | uint32 | uint64 | float64 | string |
+		int8 | int16 | int32 | int64 | []bool | []string |
+		[]uint8 | []uint16 | []uint32 | []uint64 | []float64 |
+		[]int8 | []int16 | []int32 | []int64 | List | []List |
+		Dict | []Dict
+}
+
+// Typed field getter. Returns ElementNotFound when there is no
+// element with given key and TypeAssertionFailed when element
+// value is not of type T. As uint32 and int32 values are used
+// for both 24-bit and 32-bit field types, Get[uint32] accepts
+// elements of Uint24 and Uint32 types, and so on. Objects are
+// converted between List and Dict as needed, so Get[Dict] works
+// for decoded Object elements too.
+func Get[T Value](d Dict, key uint16) (T, error) {
+	var zero T
+	elem, ok := d[key]
+	if !ok {
+		return zero, ElementNotFound
+	}
+	if v, ok := valueOf[T](elem); ok {
+		return v, nil
+	}
+	return zero, TypeAssertionFailed
+}
+
+// Typed field getter. Returns def when Get fails.
+func GetOr[T Value](d Dict, key uint16, def T) T {
+	if v, err := Get[T](d, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// Typed search. Looks for the field as SearchWithin does and
+// returns its value. Returns ElementNotFound when the field is
+// not found and TypeAssertionFailed when its value is not of
+// type T (see Get).
+func SearchAs[T Value](encoded []byte, key uint16, max, maxBytes int) (T, error) {
+	var zero T
+	elem, err := SearchWithin(encoded, key, max, maxBytes)
+	if err != nil {
+		return zero, err
+	}
+	if v, ok := valueOf[T](elem); ok {
+		return v, nil
+	}
+	return zero, fmt.Errorf("search key#%d: %w: %s found",
+		key, TypeAssertionFailed, FTypeToString(elem.FType))
+}
+
+// Typed field setter. Field type is chosen by Go type of the
+// value: 32-bit field types are used for uint32, int32, []uint32
+// and []int32 values, Object for List and List_of_Object for
+// []List. Use SetAs for 24-bit field types.
+func Set[T Value](d Dict, key uint16, v T) {
+	d.Add(key, defaultFType[T](), v)
+}
+
+// Typed field setter with explicit field type. Returns
+// TypeAssertionFailed when Go type of the value does not match
+// the field type.
+func SetAs[T Value](d Dict, key uint16, ftype uint8, v T) error {
+	if !fitsFType[T](ftype) {
+		return fmt.Errorf("set key#%d: %w: %T can not be %s",
+			key, TypeAssertionFailed, v, FTypeToString(ftype))
+	}
+	d.Add(key, ftype, v)
+	return nil
+}
+
+// Get element value as T. Nil slice values are accepted for
+// slice types, objects are converted between List and Dict.
+func valueOf[T Value](elem *Elem) (T, bool) {
+	var res T
+	if v, ok := elem.Value.(T); ok {
+		return v, true
+	}
+	switch p := any(&res).(type) {
+	case *List:
+		if d, ok := elem.Value.(Dict); ok {
+			*p = d.List()
+			return res, true
+		}
+	case *Dict:
+		if l, ok := elem.Value.(List); ok {
+			*p = l.Dict()
+			return res, true
+		}
+	case *[]List:
+		if ds, ok := elem.Value.([]Dict); ok {
+			*p = make([]List, len(ds))
+			for i, d := range ds {
+				(*p)[i] = d.List()
+			}
+			return res, true
+		}
+	case *[]Dict:
+		if ls, ok := elem.Value.([]List); ok {
+			*p = make([]Dict, len(ls))
+			for i, l := range ls {
+				(*p)[i] = l.Dict()
+			}
+			return res, true
+		}
+	}
+	// nil slice value
+	return res, elem.Value == nil && fitsFType[T](elem.FType)
+}
+
+// Check if T is Go type of values of given field type.
+func fitsFType[T Value](ftype uint8) bool {
+	switch typeOf[T]() {
+	case typeOf[Dict]():
+		return ftype == Object
+	case typeOf[[]Dict]():
+		return ftype == List_of_Object
+	}
+	return goTypes[ftype] == typeOf[T]()
+}
+
+// Get reflect.Type of type parameter.
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Field type used for Go type by default.
+func defaultFType[T Value]() uint8 {
+	var v T
+	switch any(v).(type) {
+	case bool:
+		return Bool
+	case uint8:
+		return Uint8
+	case uint16:
+		return Uint16
+	case uint32:
+		return Uint32
+	case uint64:
+		return Uint64
+	case float64:
+		return Double
+	case string:
+		return String
+	case int8:
+		return Int8
+	case int16:
+		return Int16
+	case int32:
+		return Int32
+	case int64:
+		return Int64
+	case []bool:
+		return Bitmap
+	case []string:
+		return List_of_String
+	case []uint8:
+		return List_of_Uint8
+	case []uint16:
+		return List_of_Uint16
+	case []uint32:
+		return List_of_Uint32
+	case []uint64:
+		return List_of_Uint64
+	case []float64:
+		return List_of_Double
+	case []int8:
+		return List_of_Int8
+	case []int16:
+		return List_of_Int16
+	case []int32:
+		return List_of_Int32
+	case []int64:
+		return List_of_Int64
+	case List, Dict:
+		return Object
+	}
+	return List_of_Object
+}
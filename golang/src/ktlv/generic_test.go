@@ -0,0 +1,157 @@
+package ktlv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Check Get and GetOr for one key of decoded dictionary.
+func checkGet[T Value](t *testing.T, d Dict, key uint16, expect T) {
+	t.Helper()
+	v, err := Get[T](d, key)
+	if err != nil {
+		t.Errorf("key#%d: unexpected error: %s", key, err)
+	} else if !reflect.DeepEqual(v, expect) {
+		t.Errorf("key#%d: expected %#v but %#v found", key, expect, v)
+	}
+	if v := GetOr[T](d, 0xffff, expect); !reflect.DeepEqual(v, expect) {
+		t.Errorf("key#%d: expected default but %#v found", key, v)
+	}
+}
+
+func TestGenericAccessors(t *testing.T) {
+	d := Dict{}
+	Set(d, 1, true)
+	Set(d, 2, uint8(2))
+	Set(d, 3, uint16(3))
+	Set(d, 4, uint32(4))
+	Set(d, 5, uint64(5))
+	Set(d, 6, 6.5)
+	Set(d, 7, "seven")
+	Set(d, 8, []bool{true, false})
+	Set(d, 9, int8(-9))
+	Set(d, 10, int16(-10))
+	Set(d, 11, int32(-11))
+	Set(d, 12, int64(-12))
+	Set(d, 13, []string{"a", "b"})
+	Set(d, 14, []uint8{1})
+	Set(d, 15, []uint16{1})
+	Set(d, 16, []uint32{1})
+	Set(d, 17, []uint64{1})
+	Set(d, 18, []float64{1.5})
+	Set(d, 19, []int8{-1})
+	Set(d, 20, []int16{-1})
+	Set(d, 21, []int32{-1})
+	Set(d, 22, []int64{-1})
+	Set(d, 23, List{&Elem{1, Uint8, uint8(1)}})
+	Set(d, 24, []List{{&Elem{1, Uint8, uint8(1)}}})
+	for key, ftype := range map[uint16]uint8{
+		25: Uint24, 26: Int24, 27: List_of_Uint24, 28: List_of_Int24,
+	} {
+		var err error
+		switch ftype {
+		case Uint24:
+			err = SetAs(d, key, ftype, uint32(key))
+		case Int24:
+			err = SetAs(d, key, ftype, -int32(key))
+		case List_of_Uint24:
+			err = SetAs(d, key, ftype, []uint32{uint32(key)})
+		case List_of_Int24:
+			err = SetAs(d, key, ftype, []int32{-int32(key)})
+		}
+		if err != nil {
+			t.Fatalf("set key#%d: %s", key, err)
+		}
+	}
+	if err := SetAs(d, 29, Uint24, uint16(1)); !errors.Is(err, TypeAssertionFailed) {
+		t.Errorf("expected TypeAssertionFailed but %v found", err)
+	}
+	encoded, err := d.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	d, err = DecodeDict(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	checkGet(t, d, 1, true)
+	checkGet(t, d, 2, uint8(2))
+	checkGet(t, d, 3, uint16(3))
+	checkGet(t, d, 4, uint32(4))
+	checkGet(t, d, 5, uint64(5))
+	checkGet(t, d, 6, 6.5)
+	checkGet(t, d, 7, "seven")
+	checkGet(t, d, 8, []bool{true, false})
+	checkGet(t, d, 9, int8(-9))
+	checkGet(t, d, 10, int16(-10))
+	checkGet(t, d, 11, int32(-11))
+	checkGet(t, d, 12, int64(-12))
+	checkGet(t, d, 13, []string{"a", "b"})
+	checkGet(t, d, 14, []uint8{1})
+	checkGet(t, d, 15, []uint16{1})
+	checkGet(t, d, 16, []uint32{1})
+	checkGet(t, d, 17, []uint64{1})
+	checkGet(t, d, 18, []float64{1.5})
+	checkGet(t, d, 19, []int8{-1})
+	checkGet(t, d, 20, []int16{-1})
+	checkGet(t, d, 21, []int32{-1})
+	checkGet(t, d, 22, []int64{-1})
+	checkGet(t, d, 23, List{&Elem{1, Uint8, uint8(1)}})
+	checkGet(t, d, 24, []List{{&Elem{1, Uint8, uint8(1)}}})
+	checkGet(t, d, 25, uint32(25))
+	checkGet(t, d, 26, int32(-26))
+	checkGet(t, d, 27, []uint32{27})
+	checkGet(t, d, 28, []int32{-28})
+	if d[25].FType != Uint24 || d[16].FType != List_of_Uint32 {
+		t.Errorf("unexpected field types: %v", d)
+	}
+	if _, err := Get[string](d, 100); err != ElementNotFound {
+		t.Errorf("expected ElementNotFound but %v found", err)
+	}
+	if _, err := Get[string](d, 1); err != TypeAssertionFailed {
+		t.Errorf("expected TypeAssertionFailed but %v found", err)
+	}
+	if v := GetOr(d, 1, "def"); v != "def" {
+		t.Errorf("expected default but %#v found", v)
+	}
+	d.Add(100, List_of_Uint16, nil)
+	if v, err := Get[[]uint16](d, 100); err != nil || v != nil {
+		t.Errorf("unexpected result for nil value: %v (%v)", v, err)
+	}
+}
+
+func TestGenericObjects(t *testing.T) {
+	inner := List{&Elem{1, Uint8, uint8(1)}, &Elem{2, String, "a"}}
+	d := Dict{}
+	Set(d, 1, inner.Dict())
+	Set(d, 2, []Dict{inner.Dict(), {}})
+	Set(d, 3, inner)
+	Set(d, 4, []List{inner})
+	if d[1].FType != Object || d[2].FType != List_of_Object {
+		t.Errorf("unexpected field types: %v", d)
+	}
+	if err := SetAs(d, 5, Object, Dict{}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := SetAs(d, 6, List_of_Uint8, Dict{}); !errors.Is(err, TypeAssertionFailed) {
+		t.Errorf("expected TypeAssertionFailed but %v found", err)
+	}
+	// values set as Dict are read as List and vice versa
+	checkGet(t, d, 1, inner)
+	checkGet(t, d, 1, inner.Dict())
+	checkGet(t, d, 2, []List{inner, {}})
+	checkGet(t, d, 2, []Dict{inner.Dict(), {}})
+	checkGet(t, d, 3, inner.Dict())
+	checkGet(t, d, 4, []Dict{inner.Dict()})
+	if _, err := Get[Dict](d, 5); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := Get[[]Dict](d, 1); err != TypeAssertionFailed {
+		t.Errorf("expected TypeAssertionFailed but %v found", err)
+	}
+	d.Add(7, Object, nil)
+	if v, err := Get[Dict](d, 7); err != nil || v != nil {
+		t.Errorf("unexpected result for nil value: %v (%v)", v, err)
+	}
+}
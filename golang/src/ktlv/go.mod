@@ -1,3 +1,3 @@
 module ktlv
 
-go 1.14
+go 1.23
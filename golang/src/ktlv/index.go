@@ -0,0 +1,242 @@
+package ktlv
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Index of elements of KTLV-encoded message for random access
+// to them without searching. Values are decoded from the message
+// on demand. The message must not be modified while the index is
+// in use.
+type Index struct {
+	b       []byte
+	opts    *DecodeOptions
+	entries map[uint16]IndexEntry
+}
+
+// Position of indexed element within the message.
+type IndexEntry struct {
+	Offset int
+	// field type of the value, not Chunk for chunked value
+	FType uint8
+	// bytes taken by the element including its header (or all
+	// chunks of chunked value)
+	Size int
+}
+
+// Keys of serialised index elements.
+const (
+	indexMsgLen = iota + 1
+	indexKeys
+	indexFTypes
+	indexOffsets
+	indexSizes
+)
+
+// Build index of KTLV-encoded message. Only element headers are
+// parsed, so malformed values are reported when they are decoded.
+// When the message contains several elements with the same key,
+// the last one is indexed as DecodeDict keeps it.
+func BuildIndex(encoded []byte) (*Index, error) {
+	return noLimits.BuildIndex(encoded)
+}
+
+// Build index as BuildIndex does, but enforce the limits when
+// values are decoded.
+func (o *DecodeOptions) BuildIndex(encoded []byte) (*Index, error) {
+	x := &Index{encoded, o, map[uint16]IndexEntry{}}
+	c := o.NewCursor(encoded)
+	for c.Next() {
+		if err := c.Skip(); err != nil {
+			return nil, err
+		}
+		ftype := c.FType()
+		if ftype == Chunk {
+			// chunks are already checked by Skip
+			ftype = c.RawBody()[0]
+		}
+		x.entries[c.Key()] = IndexEntry{c.Offset(), ftype,
+			c.end() - c.Offset()}
+	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Restore index serialised with Index.MarshalBinary for the
+// encoded message it was built for. Entries are checked against
+// elements of the message, but values are not decoded.
+func LoadIndex(data, encoded []byte) (*Index, error) {
+	return noLimits.LoadIndex(data, encoded)
+}
+
+// Restore index as LoadIndex does, but enforce the limits when
+// values are decoded.
+func (o *DecodeOptions) LoadIndex(data, encoded []byte) (*Index, error) {
+	if err := o.checkLen(encoded); err != nil {
+		return nil, err
+	}
+	dict, err := DecodeDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("load index: %w", err)
+	}
+	msg_len, err := Get[uint64](dict, indexMsgLen)
+	if err != nil {
+		return nil, fmt.Errorf("load index: message length: %w", err)
+	}
+	if msg_len != uint64(len(encoded)) {
+		return nil, fmt.Errorf("load index: %w: index is built for"+
+			" %d bytes but message is %d bytes", Malformed,
+			msg_len, len(encoded))
+	}
+	keys, err := Get[[]uint16](dict, indexKeys)
+	if err != nil {
+		return nil, fmt.Errorf("load index: keys: %w", err)
+	}
+	ftypes, err := Get[[]uint8](dict, indexFTypes)
+	if err != nil {
+		return nil, fmt.Errorf("load index: field types: %w", err)
+	}
+	offsets, err := Get[[]uint64](dict, indexOffsets)
+	if err != nil {
+		return nil, fmt.Errorf("load index: offsets: %w", err)
+	}
+	sizes, err := Get[[]uint64](dict, indexSizes)
+	if err != nil {
+		return nil, fmt.Errorf("load index: sizes: %w", err)
+	}
+	if len(ftypes) != len(keys) || len(offsets) != len(keys) ||
+		len(sizes) != len(keys) {
+		return nil, fmt.Errorf("load index: %w: list lengths differ",
+			Malformed)
+	}
+	x := &Index{encoded, o, make(map[uint16]IndexEntry, len(keys))}
+	for i, key := range keys {
+		if _, ok := x.entries[key]; ok {
+			return nil, fmt.Errorf("load index: %w: duplicate"+
+				" key#%d", Malformed, key)
+		}
+		if err := checkIndexEntry(encoded, key, ftypes[i],
+			offsets[i], sizes[i]); err != nil {
+			return nil, fmt.Errorf("load index: %w", err)
+		}
+		x.entries[key] = IndexEntry{int(offsets[i]), ftypes[i],
+			int(sizes[i])}
+	}
+	return x, nil
+}
+
+// Check index entry against element encoded in the message at
+// its offset. Chunks of chunked value are checked and counted to
+// its size.
+func checkIndexEntry(encoded []byte, key uint16, ftype uint8, offset, size uint64) error {
+	if uint64(len(encoded)) <= offset {
+		return fmt.Errorf("%w: key#%d at offset %d is out of"+
+			" message", Malformed, key, offset)
+	}
+	c := NewCursor(encoded[offset:])
+	if !c.Next() {
+		return fmt.Errorf("key#%d at offset %d: %w", key, offset,
+			c.Err())
+	}
+	if err := c.Skip(); err != nil {
+		return fmt.Errorf("key#%d at offset %d: %w", key, offset, err)
+	}
+	b_ftype := c.FType()
+	if b_ftype == Chunk {
+		// chunks are already checked by Skip
+		b_ftype = c.RawBody()[0]
+	}
+	if c.Key() != key || b_ftype != ftype {
+		return fmt.Errorf("%w: key#%d ftype=%d at offset %d does not"+
+			" match key#%d ftype=%d in the message", Malformed,
+			key, ftype, offset, c.Key(), b_ftype)
+	}
+	if uint64(c.end()) != size {
+		return fmt.Errorf("%w: key#%d at offset %d has size %d but"+
+			" %d bytes in the message", Malformed, key, offset,
+			size, c.end())
+	}
+	return nil
+}
+
+// Serialise index to bytes. The result is a KTLV message itself.
+// Message bytes are not included, so the index must be restored
+// with LoadIndex for the same message.
+func (x *Index) MarshalBinary() ([]byte, error) {
+	keys := x.Keys()
+	ftypes := make([]uint8, len(keys))
+	offsets := make([]uint64, len(keys))
+	sizes := make([]uint64, len(keys))
+	for i, key := range keys {
+		entry := x.entries[key]
+		ftypes[i] = entry.FType
+		offsets[i] = uint64(entry.Offset)
+		sizes[i] = uint64(entry.Size)
+	}
+	return List{
+		&Elem{indexMsgLen, Uint64, uint64(len(x.b))},
+		&Elem{indexKeys, List_of_Uint16, keys},
+		&Elem{indexFTypes, List_of_Uint8, ftypes},
+		&Elem{indexOffsets, List_of_Uint64, offsets},
+		&Elem{indexSizes, List_of_Uint64, sizes},
+	}.EncodeChunked()
+}
+
+// Check if the message has element with given key.
+func (x *Index) Has(key uint16) bool {
+	_, ok := x.entries[key]
+	return ok
+}
+
+// Return position of element with given key.
+func (x *Index) Entry(key uint16) (IndexEntry, bool) {
+	entry, ok := x.entries[key]
+	return entry, ok
+}
+
+// Return indexed keys sorted in ascending order.
+func (x *Index) Keys() []uint16 {
+	keys := make([]uint16, 0, len(x.entries))
+	for key := range x.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Decode element with given key. Returns ElementNotFound when
+// there is no such element.
+func (x *Index) Get(key uint16) (*Elem, error) {
+	entry, ok := x.entries[key]
+	if !ok {
+		return nil, ElementNotFound
+	}
+	l := &limiter{opts: x.opts, offset: entry.Offset}
+	elem, _, err := l.scan(x.b[entry.Offset : entry.Offset+entry.Size])
+	return elem, err
+}
+
+// Typed field getter for indexed message. Works as Get does for
+// Dict, but decodes only the requested value.
+func Lookup[T Value](x *Index, key uint16) (T, error) {
+	var zero T
+	entry, ok := x.entries[key]
+	if !ok {
+		return zero, ElementNotFound
+	}
+	if !fitsFType[T](entry.FType) {
+		// fail without decoding
+		return zero, TypeAssertionFailed
+	}
+	elem, err := x.Get(key)
+	if err != nil {
+		return zero, err
+	}
+	if v, ok := valueOf[T](elem); ok {
+		return v, nil
+	}
+	return zero, TypeAssertionFailed
+}
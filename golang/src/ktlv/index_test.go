@@ -0,0 +1,154 @@
+package ktlv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	long := strings.Repeat("a", 0x10010)
+	encoded, err := List{
+		&Elem{1, Uint16, uint16(1)},
+		&Elem{2, String, long},
+		&Elem{3, Object, List{&Elem{1, Bool, true}}},
+		&Elem{1, Uint16, uint16(2)},
+		&Elem{4, List_of_Int24, []int32{-1, 1}},
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	x, err := BuildIndex(encoded)
+	if err != nil {
+		t.Fatalf("build index: %s", err)
+	}
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal index: %s", err)
+	}
+	loaded, err := LoadIndex(data, encoded)
+	if err != nil {
+		t.Fatalf("load index: %s", err)
+	}
+	testset := []struct {
+		key    uint16
+		entry  IndexEntry
+		expect *Elem
+	}{
+		// the last one of duplicates as in Dict
+		{1, IndexEntry{len(encoded) - 18, Uint16, 7}, &Elem{1, Uint16, uint16(2)}},
+		{2, IndexEntry{7, String, len(encoded) - 36}, &Elem{2, String, long}},
+		{3, IndexEntry{len(encoded) - 29, Object, 11},
+			&Elem{3, Object, List{&Elem{1, Bool, true}}}},
+		{4, IndexEntry{len(encoded) - 11, List_of_Int24, 11},
+			&Elem{4, List_of_Int24, []int32{-1, 1}}},
+	}
+	for _, index := range []*Index{x, loaded} {
+		if keys := index.Keys(); len(keys) != len(testset) {
+			t.Errorf("unexpected keys: %v", keys)
+		}
+		for n, test := range testset {
+			if !index.Has(test.key) {
+				t.Errorf("#%d> key#%d is not indexed", n, test.key)
+			}
+			if entry, ok := index.Entry(test.key); !ok || entry != test.entry {
+				t.Errorf("#%d> unexpected entry: %+v", n, entry)
+			}
+			elem, err := index.Get(test.key)
+			if err != nil {
+				t.Errorf("#%d> unexpected error: %s", n, err)
+			} else if !elem.Equals(test.expect) {
+				t.Errorf("#%d> unexpected elem: %v", n, elem)
+			}
+		}
+		if index.Has(5) {
+			t.Errorf("unexpected key#5")
+		}
+		if _, err := index.Get(5); err != ElementNotFound {
+			t.Errorf("expected ElementNotFound but %v found", err)
+		}
+	}
+	if v, err := Lookup[[]int32](x, 4); err != nil || len(v) != 2 || v[0] != -1 {
+		t.Errorf("unexpected result: %v (%v)", v, err)
+	}
+	if v, err := Lookup[string](x, 2); err != nil || v != long {
+		t.Errorf("unexpected result: %v", err)
+	}
+	if _, err := Lookup[string](x, 1); err != TypeAssertionFailed {
+		t.Errorf("expected TypeAssertionFailed but %v found", err)
+	}
+	if _, err := Lookup[string](x, 5); err != ElementNotFound {
+		t.Errorf("expected ElementNotFound but %v found", err)
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	encoded, err := List{
+		&Elem{1, Uint16, uint16(1)},
+		&Elem{2, String, strings.Repeat("a", 0x10010)},
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if _, err := BuildIndex(encoded[:len(encoded)-1]); !errors.Is(err, Truncated) {
+		t.Errorf("expected Truncated but %v found", err)
+	}
+	// values are not decoded while indexing
+	broken := []byte{0, 1, Uint16, 0, 1, 1}
+	x, err := BuildIndex(broken)
+	if err != nil {
+		t.Fatalf("build index: %s", err)
+	}
+	if _, err := x.Get(1); !errors.Is(err, BadLength) {
+		t.Errorf("expected BadLength but %v found", err)
+	}
+	x, err = (&DecodeOptions{MaxStringLen: 3}).BuildIndex(encoded)
+	if err != nil {
+		t.Fatalf("build index: %s", err)
+	}
+	if _, err := Lookup[string](x, 2); !errors.Is(err, StringTooLong) {
+		t.Errorf("expected StringTooLong but %v found", err)
+	}
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal index: %s", err)
+	}
+	other := append([]byte{}, encoded...)
+	other[1] = 7
+	hostile := func(keys []uint16, offsets, sizes []uint64) []byte {
+		data, err := List{
+			&Elem{indexMsgLen, Uint64, uint64(len(encoded))},
+			&Elem{indexKeys, List_of_Uint16, keys},
+			&Elem{indexFTypes, List_of_Uint8, []uint8{Uint16, Uint16}[:len(keys)]},
+			&Elem{indexOffsets, List_of_Uint64, offsets},
+			&Elem{indexSizes, List_of_Uint64, sizes},
+		}.Encode()
+		if err != nil {
+			t.Fatalf("encode index: %s", err)
+		}
+		return data
+	}
+	if _, err := LoadIndex(hostile([]uint16{1}, []uint64{0}, []uint64{7}), encoded); err != nil {
+		t.Errorf("load index: %s", err)
+	}
+	testset := []struct {
+		data    []byte
+		encoded []byte
+	}{
+		{data[:len(data)-1], encoded},
+		{data, encoded[:len(encoded)-1]},
+		{data, other},
+		{data, append(append([]byte{}, encoded[7:]...), encoded[:7]...)},
+		{[]byte{}, encoded},
+		{hostile([]uint16{1}, []uint64{0}, []uint64{6}), encoded},
+		{hostile([]uint16{1}, []uint64{0}, []uint64{12}), encoded},
+		{hostile([]uint16{1}, []uint64{0}, []uint64{1 << 63}), encoded},
+		{hostile([]uint16{1}, []uint64{uint64(len(encoded))}, []uint64{7}), encoded},
+		{hostile([]uint16{1, 1}, []uint64{0, 0}, []uint64{7, 7}), encoded},
+	}
+	for n, test := range testset {
+		if _, err := LoadIndex(test.data, test.encoded); err == nil {
+			t.Errorf("#%d> expected error but load succeeded", n)
+		}
+	}
+}
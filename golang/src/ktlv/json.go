@@ -0,0 +1,299 @@
+package ktlv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Item field types of list field types.
+var listItemTypes = map[uint8]uint8{
+	List_of_String: String,
+	List_of_Uint8:  Uint8,
+	List_of_Uint16: Uint16,
+	List_of_Uint24: Uint24,
+	List_of_Uint32: Uint32,
+	List_of_Uint64: Uint64,
+	List_of_Double: Double,
+	List_of_Int8:   Int8,
+	List_of_Int16:  Int16,
+	List_of_Int24:  Int24,
+	List_of_Int32:  Int32,
+	List_of_Int64:  Int64,
+}
+
+// JSON representation of an element.
+type jsonElem struct {
+	Key   uint16      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// JSON representation of String value which is not valid UTF-8.
+type jsonBytes struct {
+	Hex string `json:"hex"`
+}
+
+// Same as jsonElem but with value not parsed yet.
+type rawJSONElem struct {
+	Key   uint16          `json:"key"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Encode list of elements to JSON array of objects like
+//
+//	{"key": 1, "type": "Uint64", "value": "18446744073709551615"}
+//
+// where type is field type name as returned by FTypeToString or
+// decimal field type ID for unknown field types. Values are:
+//   - Uint64 and Int64: decimal strings, as JavaScript numbers
+//     can't hold them without losing precision;
+//   - Double: numbers or "NaN", "+Inf", "-Inf" strings;
+//   - Bitmap: strings of "0" and "1" characters;
+//   - Object: arrays of elements;
+//   - String: strings or, when not valid UTF-8, objects like
+//     {"hex": "ff00"} holding the bytes of the string;
+//   - values of unknown field types: hex strings;
+//   - list types: arrays of item values;
+//   - all other types: JSON booleans, numbers and strings.
+func ToJSON(list List) ([]byte, error) {
+	elems, err := toJSONList(list)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(elems)
+}
+
+// Decode list of elements from JSON produced by ToJSON.
+// 64-bit integers may be given as JSON numbers too.
+func FromJSON(b []byte) (List, error) {
+	return fromJSONList(b)
+}
+
+// Convert list of elements to its JSON representation.
+func toJSONList(list List) ([]jsonElem, error) {
+	elems := make([]jsonElem, len(list))
+	for i, elem := range list {
+		value, err := toJSONElemValue(elem)
+		if err != nil {
+			return nil, fmt.Errorf("to json key#%d: %w", elem.Key, err)
+		}
+		name := FTypeToString(elem.FType)
+		if !isKnown(elem.FType) {
+			name = strconv.Itoa(int(elem.FType))
+		}
+		elems[i] = jsonElem{elem.Key, name, value}
+	}
+	return elems, nil
+}
+
+// Convert element value to its JSON representation.
+func toJSONElemValue(elem *Elem) (interface{}, error) {
+	if !isKnown(elem.FType) {
+		v, ok := elem.Value.(Raw)
+		if !ok {
+			return nil, fmt.Errorf("Raw expected but %T found", elem.Value)
+		}
+		return hex.EncodeToString(v), nil
+	}
+	// normalize value to the type decodeValue returns
+	encoded, err := encodeValue(elem.FType, elem.Value)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeValue(elem.FType, encoded)
+	if err != nil {
+		return nil, err
+	}
+	return toJSONValue(elem.FType, v)
+}
+
+// Convert normalized value to its JSON representation.
+func toJSONValue(ftype uint8, v interface{}) (interface{}, error) {
+	if item_type, ok := listItemTypes[ftype]; ok {
+		rv := reflect.ValueOf(v)
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			var err error
+			items[i], err = toJSONValue(item_type, rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	switch ftype {
+	case Uint64:
+		return strconv.FormatUint(v.(uint64), 10), nil
+	case Int64:
+		return strconv.FormatInt(v.(int64), 10), nil
+	case Double:
+		if f := v.(float64); math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		}
+	case String:
+		if !utf8.ValidString(v.(string)) {
+			return jsonBytes{hex.EncodeToString([]byte(v.(string)))}, nil
+		}
+	case Bitmap:
+		bits := make([]byte, len(v.([]bool)))
+		for i, bit := range v.([]bool) {
+			bits[i] = '0'
+			if bit {
+				bits[i] = '1'
+			}
+		}
+		return string(bits), nil
+	case Object:
+		return toJSONList(v.(List))
+	case List_of_Object:
+		objects := v.([]List)
+		items := make([][]jsonElem, len(objects))
+		for i, object := range objects {
+			var err error
+			if items[i], err = toJSONList(object); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return v, nil
+}
+
+// Parse JSON representation of list of elements.
+func fromJSONList(b []byte) (List, error) {
+	var elems []rawJSONElem
+	if err := json.Unmarshal(b, &elems); err != nil {
+		return nil, fmt.Errorf("from json: %w", err)
+	}
+	list := make(List, len(elems))
+	for i, elem := range elems {
+		ftype, ok := StringToFType(elem.Type)
+		if !ok {
+			id, err := strconv.ParseUint(elem.Type, 10, 8)
+			if err != nil || isKnown(uint8(id)) || id == Chunk {
+				return nil, fmt.Errorf("from json key#%d:"+
+					" bad type: %#v", elem.Key, elem.Type)
+			}
+			ftype = uint8(id)
+		}
+		value, err := fromJSONValue(ftype, elem.Value)
+		if err != nil {
+			return nil, fmt.Errorf("from json key#%d: %w", elem.Key, err)
+		}
+		list[i] = &Elem{elem.Key, ftype, value}
+	}
+	return list, nil
+}
+
+// Parse JSON representation of element value.
+func fromJSONValue(ftype uint8, b json.RawMessage) (interface{}, error) {
+	if !isKnown(ftype) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+		v, err := hex.DecodeString(s)
+		return Raw(v), err
+	}
+	if item_type, ok := listItemTypes[ftype]; ok {
+		var items []json.RawMessage
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, err
+		}
+		rv := reflect.MakeSlice(goTypes[ftype], 0, len(items))
+		for _, item := range items {
+			v, err := fromJSONValue(item_type, item)
+			if err != nil {
+				return nil, err
+			}
+			rv = reflect.Append(rv, reflect.ValueOf(v))
+		}
+		return rv.Interface(), nil
+	}
+	switch ftype {
+	case Uint24:
+		var v uint32
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		if Max_Uint24 < v {
+			return nil, fmt.Errorf("Uint24 overflow: %d", v)
+		}
+		return v, nil
+	case Int24:
+		var v int32
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		if v < Min_Int24 || Max_Int24 < v {
+			return nil, fmt.Errorf("Int24 overflow: %d", v)
+		}
+		return v, nil
+	case Uint64:
+		return strconv.ParseUint(string(unquote(b)), 10, 64)
+	case Int64:
+		return strconv.ParseInt(string(unquote(b)), 10, 64)
+	case Double:
+		if bytes.HasPrefix(b, []byte{'"'}) {
+			return strconv.ParseFloat(string(unquote(b)), 64)
+		}
+	case String:
+		if bytes.HasPrefix(b, []byte{'{'}) {
+			var v jsonBytes
+			if err := json.Unmarshal(b, &v); err != nil {
+				return nil, err
+			}
+			s, err := hex.DecodeString(v.Hex)
+			return string(s), err
+		}
+	case Bitmap:
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+		bits := make([]bool, len(s))
+		for i := 0; i < len(s); i++ {
+			if s[i] != '0' && s[i] != '1' {
+				return nil, fmt.Errorf("bad Bitmap: %#v", s)
+			}
+			bits[i] = s[i] == '1'
+		}
+		return bits, nil
+	case Object:
+		return fromJSONList(b)
+	case List_of_Object:
+		var items []json.RawMessage
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, err
+		}
+		objects := make([]List, len(items))
+		for i, item := range items {
+			var err error
+			if objects[i], err = fromJSONList(item); err != nil {
+				return nil, err
+			}
+		}
+		return objects, nil
+	}
+	v := reflect.New(goTypes[ftype])
+	if err := json.Unmarshal(b, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
+
+// Strip quotes from JSON string token without unescaping it.
+// Numbers are returned as is.
+func unquote(b []byte) []byte {
+	if 2 <= len(b) && b[0] == '"' && b[len(b)-1] == '"' {
+		return b[1 : len(b)-1]
+	}
+	return b
+}
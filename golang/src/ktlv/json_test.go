@@ -0,0 +1,123 @@
+package ktlv
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	list := List{
+		&Elem{1, Uint64, Max_Uint64},
+		&Elem{2, Int64, Min_Int64},
+		&Elem{3, Bitmap, []bool{true, false, true}},
+		&Elem{4, List_of_Uint8, []uint8{1, 2}},
+		&Elem{5, Double, math.Inf(-1)},
+		&Elem{6, Object, List{&Elem{1, Bool, true}}},
+		&Elem{7, 200, Raw{0xab, 0xcd}},
+		&Elem{8, List_of_Int64, []int64{-1}},
+	}
+	encoded, err := ToJSON(list)
+	if err != nil {
+		t.Fatalf("to json: %s", err)
+	}
+	expect := `[{"key":1,"type":"Uint64","value":"18446744073709551615"},` +
+		`{"key":2,"type":"Int64","value":"-9223372036854775808"},` +
+		`{"key":3,"type":"Bitmap","value":"101"},` +
+		`{"key":4,"type":"List_of_Uint8","value":[1,2]},` +
+		`{"key":5,"type":"Double","value":"-Inf"},` +
+		`{"key":6,"type":"Object","value":[{"key":1,"type":"Bool","value":true}]},` +
+		`{"key":7,"type":"200","value":"abcd"},` +
+		`{"key":8,"type":"List_of_Int64","value":["-1"]}]`
+	if string(encoded) != expect {
+		t.Errorf("expected %s but %s found", expect, encoded)
+	}
+	decoded, err := FromJSON(encoded)
+	if err != nil {
+		t.Fatalf("from json: %s", err)
+	}
+	for i, elem := range list {
+		if !elem.Equals(decoded[i]) {
+			t.Errorf("elems #%d differ: %v and %v", i, elem, decoded[i])
+		}
+	}
+	nan, err := FromJSON([]byte(`[{"key":1,"type":"double","value":"NaN"}]`))
+	if err != nil || !math.IsNaN(nan[0].Value.(float64)) {
+		t.Errorf("unexpected NaN result: %v (%v)", nan, err)
+	}
+}
+
+func TestJSONBytes(t *testing.T) {
+	list := List{
+		&Elem{1, String, "\xff\x00a"},
+		&Elem{2, List_of_String, []string{"ok", "\xc3"}},
+		&Elem{3, String, "\u00e9"},
+	}
+	encoded, err := ToJSON(list)
+	if err != nil {
+		t.Fatalf("to json: %s", err)
+	}
+	expect := `[{"key":1,"type":"String","value":{"hex":"ff0061"}},` +
+		`{"key":2,"type":"List_of_String","value":["ok",{"hex":"c3"}]},` +
+		`{"key":3,"type":"String","value":"é"}]`
+	if string(encoded) != expect {
+		t.Errorf("expected %s but %s found", expect, encoded)
+	}
+	decoded, err := FromJSON(encoded)
+	if err != nil {
+		t.Fatalf("from json: %s", err)
+	}
+	for i, elem := range list {
+		if !elem.Equals(decoded[i]) {
+			t.Errorf("elems #%d differ: %v and %v", i, elem, decoded[i])
+		}
+	}
+	testset := []string{
+		`[{"key": 1, "type": "String", "value": {"hex": "xyz"}}]`,
+		`[{"key": 1, "type": "String", "value": {"hex": 1}}]`,
+	}
+	for n, test := range testset {
+		if _, err := FromJSON([]byte(test)); err == nil {
+			t.Errorf("#%d> expected error", n)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	list, err := FromJSON([]byte(`[
+		{"key": 1, "type": "uint64", "value": 18446744073709551615},
+		{"key": 2, "type": "List_of_Object", "value": [[], null]},
+		{"key": 3, "type": "List_of_Uint24", "value": null}]`))
+	if err != nil {
+		t.Fatalf("from json: %s", err)
+	}
+	expect := List{
+		&Elem{1, Uint64, Max_Uint64},
+		&Elem{2, List_of_Object, []List{{}, {}}},
+		&Elem{3, List_of_Uint24, []uint32{}},
+	}
+	for i, elem := range expect {
+		if !elem.Equals(list[i]) {
+			t.Errorf("elems #%d differ: %v and %v", i, elem, list[i])
+		}
+	}
+	testset := []string{
+		`{}`,
+		`[{"key": 1, "type": "Foo", "value": 1}]`,
+		`[{"key": 1, "type": "1", "value": 1}]`,
+		`[{"key": 1, "type": "100", "value": ""}]`,
+		`[{"key": 1, "type": "Uint8", "value": 256}]`,
+		`[{"key": 1, "type": "Uint24", "value": 16777216}]`,
+		`[{"key": 1, "type": "Int24", "value": -8388609}]`,
+		`[{"key": 1, "type": "Int64", "value": "9223372036854775808"}]`,
+		`[{"key": 1, "type": "Bitmap", "value": "012"}]`,
+		`[{"key": 1, "type": "List_of_Int8", "value": [1, 128]}]`,
+		`[{"key": 1, "type": "Object", "value": [{"key": 1}]}]`,
+		`[{"key": 1, "type": "200", "value": "xyz"}]`,
+		`[{"key": 65536, "type": "Bool", "value": true}]`,
+	}
+	for n, test := range testset {
+		if _, err := FromJSON([]byte(test)); err == nil {
+			t.Errorf("#%d> expected error", n)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package ktlv
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 const (
 	Bool   = 0
@@ -17,6 +21,7 @@ const (
 	Int24  = 11
 	Int32  = 12
 	Int64  = 13
+	Object = 14
 
 	List_of_String = 50
 	List_of_Uint8  = 51
@@ -30,6 +35,11 @@ const (
 	List_of_Int24  = 59
 	List_of_Int32  = 60
 	List_of_Int64  = 61
+	List_of_Object = 62
+
+	// Service field type for parts of values longer than 65535
+	// bytes. See Elem.EncodeChunked for details.
+	Chunk = 100
 
 	Min_Int8   = int8(-0x80)
 	Min_Int16  = int16(-0x8000)
@@ -68,6 +78,7 @@ var t2s = map[uint8]string{
 	Int24:          "Int24",
 	Int32:          "Int32",
 	Int64:          "Int64",
+	Object:         "Object",
 	List_of_String: "List_of_String",
 	List_of_Uint8:  "List_of_Uint8",
 	List_of_Uint16: "List_of_Uint16",
@@ -80,13 +91,67 @@ var t2s = map[uint8]string{
 	List_of_Int24:  "List_of_Int24",
 	List_of_Int32:  "List_of_Int32",
 	List_of_Int64:  "List_of_Int64",
+	List_of_Object: "List_of_Object",
 }
 
 func FTypeToString(t uint8) string {
 	return t2s[t]
 }
 
+// Check if field type is known to the library.
+func isKnown(t uint8) bool {
+	_, ok := t2s[t]
+	return ok
+}
+
+// Resolve field type name (case insensitive) to field type ID.
+func StringToFType(s string) (uint8, bool) {
+	for t, name := range t2s {
+		if strings.EqualFold(name, s) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
 var (
 	ElementNotFound     = errors.New("no such element")
 	TypeAssertionFailed = errors.New("unexpected element type")
+	BodyTooLong         = errors.New("element body is too long")
+
+	// Causes of DecodeError.
+	Truncated    = errors.New("truncated input")
+	BadLength    = errors.New("bad body length")
+	Malformed    = errors.New("malformed element")
+	UnknownFType = errors.New("unknown field type")
+
+	// Errors for exceeded DecodeOptions limits.
+	TooManyElements  = errors.New("too many elements")
+	MessageTooLong   = errors.New("message is too long")
+	TooManyListItems = errors.New("too many list items")
+	StringTooLong    = errors.New("string is too long")
+	NestingTooDeep   = errors.New("objects are nested too deep")
 )
+
+// Error returned by decoding functions for malformed input.
+// Err is the cause, one of Truncated, BadLength, Malformed,
+// UnknownFType or limit errors of DecodeOptions, possibly wrapped
+// with some details. Offset is the position of the broken element
+// within the message. For elements of nested objects it points
+// to the nested element itself. Key and FType are zero when
+// element header is incomplete.
+type DecodeError struct {
+	Offset int
+	Key    uint16
+	FType  uint8
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode key#%d ftype=%d at offset %d: %s",
+		e.Key, e.FType, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
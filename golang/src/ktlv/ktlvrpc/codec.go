@@ -0,0 +1,265 @@
+// Package ktlvrpc implements KTLV-based ClientCodec and
+// ServerCodec for the net/rpc package.
+//
+// Each request and response is sent as one frame (see package
+// ktlv/frame). Frame payload starts with header elements with
+// reserved keys (KeyServiceMethod, KeySeq and, for responses
+// only, KeyError) followed by elements of the encoded body.
+//
+// Bodies of types ktlv.List and ktlv.Dict (or pointers to them)
+// are encoded as is, other bodies must be structs and are encoded
+// with ktlv.Marshal. Body elements may not use reserved keys.
+package ktlvrpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+
+	"ktlv"
+	"ktlv/frame"
+)
+
+// Reserved element keys of the message header.
+const (
+	KeyServiceMethod = 0xffff // String
+	KeySeq           = 0xfffe // Uint64
+	KeyError         = 0xfffd // String
+
+	// All keys starting from this one are reserved.
+	MinReservedKey = 0xfff0
+)
+
+// Message header.
+type header struct {
+	ServiceMethod string
+	Seq           uint64
+	Error         string
+}
+
+// Common part of client and server codecs.
+type codec struct {
+	c  io.Closer
+	fr *frame.FrameReader
+	fw *frame.FrameWriter
+
+	// body of the last read message
+	body []byte
+	// net/rpc never writes messages concurrently,
+	// so the buffer can be reused
+	buf bytes.Buffer
+}
+
+func newCodec(conn io.ReadWriteCloser) codec {
+	return codec{
+		c:  conn,
+		fr: frame.NewFrameReader(conn),
+		fw: frame.NewFrameWriter(conn),
+	}
+}
+
+// Encode header and write it along with encoded body
+// as one frame.
+func (c *codec) write(h *header, withError bool, body []byte) error {
+	c.buf.Reset()
+	elems := []*ktlv.Elem{
+		{Key: KeyServiceMethod, FType: ktlv.String, Value: h.ServiceMethod},
+		{Key: KeySeq, FType: ktlv.Uint64, Value: h.Seq},
+	}
+	if withError {
+		elems = append(elems,
+			&ktlv.Elem{Key: KeyError, FType: ktlv.String, Value: h.Error})
+	}
+	for _, elem := range elems {
+		if _, err := elem.WriteTo(&c.buf); err != nil {
+			return err
+		}
+	}
+	c.buf.Write(body)
+	return c.fw.WriteFrame(c.buf.Bytes())
+}
+
+// Read next frame, decode its header and keep the body
+// for the following readBody call.
+func (c *codec) readHeader(h *header) error {
+	payload, err := c.fr.ReadFrame()
+	if err != nil {
+		return err
+	}
+	body := payload
+	for 0 < len(body) {
+		key, _, _, tail, err := ktlv.ScanRaw(body)
+		if err != nil {
+			return err
+		}
+		if key < MinReservedKey {
+			break
+		}
+		body = tail
+	}
+	dict, err := ktlv.DecodeDict(payload[:len(payload)-len(body)])
+	if err != nil {
+		return err
+	}
+	if h.ServiceMethod, err = dict.GetString(KeyServiceMethod); err != nil {
+		return fmt.Errorf("ktlvrpc: service method: %w", err)
+	}
+	if h.Seq, err = dict.GetUint64(KeySeq); err != nil {
+		return fmt.Errorf("ktlvrpc: seq: %w", err)
+	}
+	h.Error = dict.GetStringDef(KeyError, "")
+	c.body = body
+	return nil
+}
+
+// Decode body of the last read message to v.
+// Body is discarded when v is nil.
+func (c *codec) readBody(v interface{}) error {
+	body := c.body
+	c.body = nil
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *ktlv.List:
+		list, err := ktlv.DecodeList(body)
+		if err != nil {
+			return err
+		}
+		*v = list
+		return nil
+	case *ktlv.Dict:
+		dict, err := ktlv.DecodeDict(body)
+		if err != nil {
+			return err
+		}
+		*v = dict
+		return nil
+	}
+	return ktlv.Unmarshal(body, v)
+}
+
+func (c *codec) Close() error {
+	return c.c.Close()
+}
+
+// Encode message body. See package doc for supported types.
+func encodeBody(body interface{}) (encoded []byte, err error) {
+	switch v := body.(type) {
+	case ktlv.List:
+		encoded, err = v.EncodeChunked()
+	case *ktlv.List:
+		encoded, err = v.EncodeChunked()
+	case ktlv.Dict:
+		encoded, err = v.EncodeChunked()
+	case *ktlv.Dict:
+		encoded, err = v.EncodeChunked()
+	default:
+		encoded, err = ktlv.Marshal(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for tail := encoded; 0 < len(tail); {
+		var key uint16
+		if key, _, _, tail, err = ktlv.ScanRaw(tail); err != nil {
+			return nil, err
+		}
+		if MinReservedKey <= key {
+			return nil, fmt.Errorf("ktlvrpc: body uses"+
+				" reserved key#%d", key)
+		}
+	}
+	return encoded, nil
+}
+
+type clientCodec struct {
+	codec
+	h header
+}
+
+// Create new rpc.ClientCodec using KTLV on conn.
+func NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return &clientCodec{codec: newCodec(conn)}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	encoded, err := encodeBody(body)
+	if err != nil {
+		return err
+	}
+	h := header{ServiceMethod: r.ServiceMethod, Seq: r.Seq}
+	return c.write(&h, false, encoded)
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	if err := c.readHeader(&c.h); err != nil {
+		return err
+	}
+	r.ServiceMethod = c.h.ServiceMethod
+	r.Seq = c.h.Seq
+	r.Error = c.h.Error
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(body interface{}) error {
+	return c.readBody(body)
+}
+
+type serverCodec struct {
+	codec
+	h header
+}
+
+// Create new rpc.ServerCodec using KTLV on conn.
+func NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec{codec: newCodec(conn)}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.readHeader(&c.h); err != nil {
+		return err
+	}
+	r.ServiceMethod = c.h.ServiceMethod
+	r.Seq = c.h.Seq
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	return c.readBody(body)
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	h := header{ServiceMethod: r.ServiceMethod, Seq: r.Seq, Error: r.Error}
+	encoded, err := encodeBody(body)
+	if err != nil {
+		// the client must not wait for the response forever
+		h.Error = err.Error()
+		if werr := c.write(&h, true, nil); werr != nil {
+			return werr
+		}
+		return err
+	}
+	return c.write(&h, true, encoded)
+}
+
+// Create new rpc.Client using KTLV on conn.
+func NewClient(conn io.ReadWriteCloser) *rpc.Client {
+	return rpc.NewClientWithCodec(NewClientCodec(conn))
+}
+
+// Connect to RPC server at the specified network address.
+func Dial(network, address string) (*rpc.Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Serve single connection with rpc.DefaultServer using KTLV.
+// Blocks until the client hangs up.
+func ServeConn(conn io.ReadWriteCloser) {
+	rpc.ServeCodec(NewServerCodec(conn))
+}
@@ -0,0 +1,90 @@
+package ktlvrpc
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+
+	"ktlv"
+)
+
+type Args struct {
+	A int32 `ktlv:"1"`
+	B int32 `ktlv:"2"`
+}
+
+type Reply struct {
+	Sum int32 `ktlv:"1"`
+}
+
+type Arith struct{}
+
+func (Arith) Add(args *Args, reply *Reply) error {
+	reply.Sum = args.A + args.B
+	return nil
+}
+
+func (Arith) Fail(args *Args, reply *Reply) error {
+	return errors.New("failed")
+}
+
+func (Arith) Echo(args *ktlv.Dict, reply *ktlv.Dict) error {
+	*reply = *args
+	return nil
+}
+
+func (Arith) Bad(args *Args, reply *int) error {
+	return nil
+}
+
+func TestRPC(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.Register(Arith{}); err != nil {
+		t.Fatalf("register: %s", err)
+	}
+	cli, srv := net.Pipe()
+	go server.ServeCodec(NewServerCodec(srv))
+	client := NewClient(cli)
+	defer client.Close()
+
+	var reply Reply
+	if err := client.Call("Arith.Add", &Args{7, -2}, &reply); err != nil {
+		t.Fatalf("call: %s", err)
+	}
+	if reply.Sum != 5 {
+		t.Errorf("expected 5 but %d found", reply.Sum)
+	}
+	err := client.Call("Arith.Fail", &Args{}, &reply)
+	if _, ok := err.(rpc.ServerError); !ok || err.Error() != "failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = client.Call("Arith.Nope", &Args{}, &reply)
+	if _, ok := err.(rpc.ServerError); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+	args := ktlv.Dict{}
+	args.Add(1, ktlv.String, strings.Repeat("a", 0x20000))
+	var echo ktlv.Dict
+	if err := client.Call("Arith.Echo", args, &echo); err != nil {
+		t.Fatalf("call: %s", err)
+	}
+	if !echo[1].Equals(args[1]) {
+		t.Errorf("unexpected echo: %v", echo)
+	}
+	var n int
+	err = client.Call("Arith.Bad", &Args{}, &n)
+	if _, ok := err.(rpc.ServerError); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// connection is still usable
+	if err := client.Call("Arith.Add", &Args{1, 1}, &reply); err != nil || reply.Sum != 2 {
+		t.Errorf("unexpected result: %d, %v", reply.Sum, err)
+	}
+	bad := ktlv.Dict{}
+	bad.Add(KeySeq, ktlv.Uint8, uint8(1))
+	if err := client.Call("Arith.Echo", bad, &echo); err == nil {
+		t.Errorf("expected error for reserved key")
+	}
+}
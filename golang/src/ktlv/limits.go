@@ -0,0 +1,341 @@
+package ktlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Resource limits for decoding messages from untrusted sources.
+// Zero value of any limit means no limit. Zero DecodeOptions
+// decode messages exactly as package level functions do.
+type DecodeOptions struct {
+	// Max number of decoded elements, including elements of
+	// nested objects. Exceeding it fails with TooManyElements.
+	MaxElements int
+	// Max length of encoded message. Exceeding it fails with
+	// MessageTooLong. Bytes copied to join chunked values are
+	// counted against it separately.
+	MaxBytes int
+	// Max number of items of one list element, including bits
+	// of Bitmap and objects of List_of_Object. Exceeding it
+	// fails with TooManyListItems.
+	MaxListItems int
+	// Max length of String value or List_of_String item.
+	// Exceeding it fails with StringTooLong.
+	MaxStringLen int
+	// Max nesting depth of objects: value of Object element of
+	// the message is at depth 1, objects within it are at depth
+	// 2 and so on. Exceeding it fails with NestingTooDeep.
+	MaxDepth int
+
+	// Decode String and List_of_String values (including ones of
+	// nested objects) without copying: they share memory with the
+	// input buffer. This saves allocations, but the buffer must
+	// not be modified while decoded elements are in use.
+	// List_of_Uint8 and Raw values share memory with the input
+	// buffer regardless of this option.
+	ZeroCopy bool
+}
+
+// Options used by package level functions.
+var noLimits = &DecodeOptions{}
+
+// Item lengths of fixed width list field types.
+var listItemLens = map[uint8]int{
+	List_of_Uint8:  1,
+	List_of_Uint16: 2,
+	List_of_Uint24: 3,
+	List_of_Uint32: 4,
+	List_of_Uint64: 8,
+	List_of_Double: 8,
+	List_of_Int8:   1,
+	List_of_Int16:  2,
+	List_of_Int24:  3,
+	List_of_Int32:  4,
+	List_of_Int64:  8,
+}
+
+// Decode data from byte buffer as DecodeList does, but enforce
+// the limits.
+func (o *DecodeOptions) DecodeList(bytes []byte) (List, error) {
+	res := List{}
+	c := o.NewCursor(bytes)
+	for _, elem := range c.elems() {
+		res = append(res, elem)
+	}
+	return res, c.Err()
+}
+
+// Decode body of nested object as DecodeList does, but reject
+// Chunk elements. Offsets of returned *DecodeError are relative
+// to b.
+func (o *DecodeOptions) decodeObject(b []byte) (List, error) {
+	res := List{}
+	c := o.NewCursor(b)
+	c.nested = true
+	for _, elem := range c.elems() {
+		res = append(res, elem)
+	}
+	return res, c.Err()
+}
+
+// Decode data from byte buffer as DecodeDict does, but enforce
+// the limits.
+func (o *DecodeOptions) DecodeDict(bytes []byte) (Dict, error) {
+	res := Dict{}
+	c := o.NewCursor(bytes)
+	for key, elem := range c.elems() {
+		res[key] = elem
+	}
+	return res, c.Err()
+}
+
+// Decode element with specified key as DecodeElem does, but
+// enforce the limits.
+func (o *DecodeOptions) DecodeElem(b []byte, key uint16) (*Elem, error) {
+	c := o.NewCursor(b)
+	for c.Next() {
+		if c.Key() == key {
+			return c.elem()
+		}
+	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ElementNotFound
+}
+
+// Search specific field as Search does, but enforce the limits.
+func (o *DecodeOptions) Search(encoded []byte, key uint16, max int) (*Elem, error) {
+	c := o.NewCursor(encoded)
+	for ; 0 < max; max-- {
+		if !c.Next() {
+			if err := c.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		// other elements are skipped by Next without decoding
+		if c.Key() == key {
+			return c.elem()
+		}
+	}
+	return nil, nil
+}
+
+// Search specific field as SearchWithin does, but enforce the
+// limits.
+func (o *DecodeOptions) SearchWithin(encoded []byte, key uint16, max, maxBytes int) (*Elem, error) {
+	c := o.NewCursor(encoded)
+	for ; 0 < max; max-- {
+		if 0 < maxBytes && maxBytes <= c.end() || !c.Next() {
+			break
+		}
+		if err := c.Skip(); err != nil {
+			return nil, err
+		}
+		if 0 < maxBytes && maxBytes < c.end() {
+			break
+		}
+		if c.Key() == key {
+			return c.elem()
+		}
+	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ElementNotFound
+}
+
+// Check encoded message length.
+func (o *DecodeOptions) checkLen(b []byte) error {
+	if 0 < o.MaxBytes && o.MaxBytes < len(b) {
+		return fmt.Errorf("decode: %w: %d bytes", MessageTooLong, len(b))
+	}
+	return nil
+}
+
+// Decoding state for limits spanning several elements.
+// Nil opts mean no limits.
+type limiter struct {
+	opts     *DecodeOptions
+	elements int
+	// bytes copied to join chunked values
+	joined int
+	// nesting depth of the object being checked
+	depth int
+	// offset of the next element within the message
+	offset int
+}
+
+// Decode next element from byte slice. Returns io.EOF if bytes
+// is empty and *DecodeError on malformed input.
+func (l *limiter) scan(bytes []byte) (elem *Elem, tail []byte, err error) {
+	key, ftype, body, tail, err := ScanRaw(bytes)
+	if err == io.EOF {
+		return nil, tail, err
+	} else if err != nil {
+		return nil, tail, l.error(key, ftype, err)
+	}
+	chunked := ftype == Chunk
+	if chunked {
+		ftype, body, tail, err = joinChunks(key, body, tail)
+		if err != nil {
+			return nil, nil, l.error(key, Chunk, err)
+		}
+		if err := l.join(len(body)); err != nil {
+			return nil, nil, l.error(key, ftype, err)
+		}
+	}
+	if err := l.check(key, ftype, body, l.offset+5); err != nil {
+		return nil, nil, l.error(key, ftype, err)
+	}
+	elem, err = decodeElem(key, ftype, body, l.opts != nil && l.opts.ZeroCopy)
+	if err != nil {
+		// offsets within joined chunks make no sense
+		if err, ok := err.(*DecodeError); ok && !chunked {
+			err.Offset += l.offset + 5
+			return nil, nil, err
+		}
+		return nil, nil, l.error(key, ftype, err)
+	}
+	l.offset += len(bytes) - len(tail)
+	return elem, tail, nil
+}
+
+// Make error for the element at the current offset unless
+// err is already *DecodeError.
+func (l *limiter) error(key uint16, ftype uint8, err error) error {
+	if _, ok := err.(*DecodeError); ok {
+		return err
+	}
+	return &DecodeError{l.offset, key, ftype, err}
+}
+
+// Count bytes copied to join chunked value.
+func (l *limiter) join(n int) error {
+	l.joined += n
+	if o := l.opts; o != nil && 0 < o.MaxBytes && o.MaxBytes < l.joined {
+		return fmt.Errorf("%w: %d bytes of chunked values",
+			MessageTooLong, l.joined)
+	}
+	return nil
+}
+
+// Check element body against the limits before it is decoded.
+// Base is the body offset within the message. Malformed bodies
+// are not reported here, they are left for the decoder.
+func (l *limiter) check(key uint16, ftype uint8, body []byte, base int) error {
+	o := l.opts
+	if o == nil || (o.MaxElements == 0 && o.MaxBytes == 0 &&
+		o.MaxListItems == 0 && o.MaxStringLen == 0 && o.MaxDepth == 0) {
+		return nil
+	}
+	l.elements++
+	if 0 < o.MaxElements && o.MaxElements < l.elements {
+		return fmt.Errorf("%w: more than %d",
+			TooManyElements, o.MaxElements)
+	}
+	items := 0
+	switch ftype {
+	case String:
+		if 0 < o.MaxStringLen && o.MaxStringLen < len(body) {
+			return fmt.Errorf("%w: %d bytes", StringTooLong, len(body))
+		}
+	case Bitmap:
+		if 0 < len(body) {
+			items = (len(body)-1)*8 - int(body[0])
+		}
+	case List_of_String:
+		for tail := body; 2 <= len(tail); items++ {
+			n := int(binary.BigEndian.Uint16(tail))
+			if 0 < o.MaxStringLen && o.MaxStringLen < n {
+				return fmt.Errorf("%w: %d bytes", StringTooLong, n)
+			}
+			if len(tail) < 2+n {
+				break
+			}
+			tail = tail[2+n:]
+		}
+	case Object:
+		return l.checkObject(body, base)
+	case List_of_Object:
+		if err := l.enter(); err != nil {
+			return err
+		}
+		defer l.leave()
+		for tail := body; 2 <= len(tail); {
+			items++
+			if 0 < o.MaxListItems && o.MaxListItems < items {
+				break
+			}
+			n := int(binary.BigEndian.Uint16(tail))
+			if len(tail) < 2+n {
+				break
+			}
+			offset := base + len(body) - len(tail) + 2
+			if err := l.checkElems(tail[2:2+n], offset); err != nil {
+				return err
+			}
+			tail = tail[2+n:]
+		}
+	default:
+		if n, ok := listItemLens[ftype]; ok {
+			items = len(body) / n
+		}
+	}
+	if 0 < o.MaxListItems && o.MaxListItems < items {
+		return fmt.Errorf("%w: more than %d",
+			TooManyListItems, o.MaxListItems)
+	}
+	return nil
+}
+
+// Check nested object against the limits. Base is the object
+// offset within the message.
+func (l *limiter) checkObject(b []byte, base int) error {
+	if err := l.enter(); err != nil {
+		return err
+	}
+	defer l.leave()
+	return l.checkElems(b, base)
+}
+
+// Go one level deeper into nested objects.
+func (l *limiter) enter() error {
+	l.depth++
+	if o := l.opts; 0 < o.MaxDepth && o.MaxDepth < l.depth {
+		return fmt.Errorf("%w: more than %d", NestingTooDeep, o.MaxDepth)
+	}
+	return nil
+}
+
+// Go back one level up from nested objects.
+func (l *limiter) leave() {
+	l.depth--
+}
+
+// Check elements of nested object against the limits.
+// Base is the object offset within the message.
+func (l *limiter) checkElems(b []byte, base int) error {
+	for offset := base; 0 < len(b); {
+		key, ftype, body, tail, err := ScanRaw(b)
+		if err != nil {
+			return nil
+		}
+		if ftype == Chunk {
+			// reported by the decoder
+			return nil
+		}
+		if err := l.check(key, ftype, body, offset+5); err != nil {
+			if _, ok := err.(*DecodeError); ok {
+				return err
+			}
+			return &DecodeError{offset, key, ftype, err}
+		}
+		offset += len(b) - len(tail)
+		b = tail
+	}
+	return nil
+}
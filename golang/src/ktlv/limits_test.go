@@ -0,0 +1,123 @@
+package ktlv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOptions(t *testing.T) {
+	encoded, err := List{
+		&Elem{1, String, "abcd"},
+		&Elem{2, List_of_String, []string{"", "", "abc"}},
+		&Elem{3, List_of_Uint16, []uint16{1, 2, 3, 4}},
+		&Elem{4, Bitmap, []bool{true, false}},
+		&Elem{5, Object, List{&Elem{1, Uint8, uint8(1)},
+			&Elem{2, String, "abcde"}}},
+		&Elem{6, List_of_Object, []List{{}, {}}},
+		&Elem{7, String, strings.Repeat("a", 0x10010)},
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	testset := []struct {
+		opts DecodeOptions
+		err  error
+	}{
+		{DecodeOptions{}, nil},
+		{DecodeOptions{MaxElements: 9, MaxBytes: len(encoded),
+			MaxListItems: 4, MaxStringLen: 0x10010}, nil},
+		{DecodeOptions{MaxElements: 8}, TooManyElements},
+		{DecodeOptions{MaxBytes: len(encoded) - 1}, MessageTooLong},
+		{DecodeOptions{MaxListItems: 3}, TooManyListItems},
+		{DecodeOptions{MaxStringLen: 0x1000f}, StringTooLong},
+		{DecodeOptions{MaxDepth: 1}, nil},
+	}
+	for n, test := range testset {
+		if _, err := test.opts.DecodeList(encoded); !errors.Is(err, test.err) {
+			t.Errorf("#%d> list: expected %v but %v found", n, test.err, err)
+		}
+		if _, err := test.opts.DecodeDict(encoded); !errors.Is(err, test.err) {
+			t.Errorf("#%d> dict: expected %v but %v found", n, test.err, err)
+		}
+	}
+	// limits apply to elements really decoded
+	elemset := []struct {
+		opts DecodeOptions
+		key  uint16
+		err  error
+	}{
+		{DecodeOptions{MaxStringLen: 4}, 1, nil},
+		{DecodeOptions{MaxStringLen: 4}, 5, StringTooLong},
+		{DecodeOptions{MaxStringLen: 2}, 2, StringTooLong},
+		{DecodeOptions{MaxListItems: 2}, 2, TooManyListItems},
+		{DecodeOptions{MaxListItems: 1}, 4, TooManyListItems},
+		{DecodeOptions{MaxListItems: 1}, 6, TooManyListItems},
+		{DecodeOptions{MaxElements: 2}, 5, TooManyElements},
+		{DecodeOptions{MaxElements: 3}, 5, nil},
+		{DecodeOptions{MaxBytes: 10}, 1, MessageTooLong},
+	}
+	for n, test := range elemset {
+		if _, err := test.opts.DecodeElem(encoded, test.key); !errors.Is(err, test.err) {
+			t.Errorf("#%d> elem: expected %v but %v found", n, test.err, err)
+		}
+		if _, err := test.opts.Search(encoded, test.key, 10); !errors.Is(err, test.err) {
+			t.Errorf("#%d> search: expected %v but %v found", n, test.err, err)
+		}
+	}
+	opts := DecodeOptions{MaxElements: 2}
+	if elem, err := opts.Search(encoded, 2, 10); err != nil || elem == nil {
+		t.Errorf("unexpected search result: %v (%v)", elem, err)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	object := List{&Elem{1, Uint8, uint8(1)}}
+	objects := List{&Elem{1, Uint8, uint8(1)}}
+	for i := 0; i < 3; i++ {
+		object = List{&Elem{1, Object, object}}
+		objects = List{&Elem{1, List_of_Object, []List{{}, objects}}}
+	}
+	testset := []struct {
+		list  List
+		depth int
+		err   error
+	}{
+		{object, 3, nil},
+		{object, 2, NestingTooDeep},
+		{objects, 3, nil},
+		{objects, 2, NestingTooDeep},
+	}
+	for n, test := range testset {
+		encoded, err := test.list.Encode()
+		if err != nil {
+			t.Fatalf("#%d> encode: %s", n, err)
+		}
+		opts := DecodeOptions{MaxDepth: test.depth}
+		if _, err := opts.DecodeList(encoded); !errors.Is(err, test.err) {
+			t.Errorf("#%d> expected %v but %v found", n, test.err, err)
+		}
+		if _, err := opts.NewDecoder(bytes.NewReader(encoded)).Next(); !errors.Is(err, test.err) {
+			t.Errorf("#%d> decoder: expected %v but %v found", n, test.err, err)
+		}
+	}
+	// chunked objects nested deep used to be joined at each level
+	deep, err := (&Elem{1, String, strings.Repeat("a", 0x10000)}).EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	for i := 0; i < 100; i++ {
+		deep = appendChunks(nil, 1, Object, deep)
+	}
+	opts := DecodeOptions{MaxBytes: 1 << 20}
+	if opts.MaxBytes < len(deep) {
+		t.Fatalf("expected message within MaxBytes but %d bytes found", len(deep))
+	}
+	if _, err := opts.DecodeList(deep); err == nil {
+		t.Errorf("expected error")
+	}
+	if _, err := opts.NewDecoder(bytes.NewReader(deep)).Next(); err == nil {
+		t.Errorf("expected decoder error")
+	}
+}
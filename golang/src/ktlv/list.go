@@ -1,38 +1,60 @@
 package ktlv
 
-import "bytes"
-
 type List []*Elem
 
 // Encode input data to byte buffer.
 func (d List) Encode() ([]byte, error) {
-	buffer := &bytes.Buffer{}
+	return d.AppendTo(nil)
+}
+
+// Encode input data to byte buffer as Encode does, but split
+// values longer than 65535 bytes. See Elem.EncodeChunked.
+func (d List) EncodeChunked() ([]byte, error) {
+	return d.appendTo(nil, appendElemChunked)
+}
+
+// Append encoded elements to dst. See AppendElem. On error
+// returns dst unchanged.
+func (d List) AppendTo(dst []byte) ([]byte, error) {
+	return d.appendTo(dst, AppendElem)
+}
+
+// Append all elements to dst with given element encoder.
+func (d List) appendTo(dst []byte, appendElem func([]byte, *Elem) ([]byte, error)) ([]byte, error) {
+	res := dst
+	var err error
 	for _, elem := range d {
-		encoded, err := elem.Encode()
-		if err != nil {
-			return nil, err
-		}
-		if _, err := buffer.Write(encoded); err != nil {
-			return nil, err
+		if res, err = appendElem(res, elem); err != nil {
+			return dst, err
 		}
 	}
-	return buffer.Bytes(), nil
+	return res, nil
 }
 
 // Decode data from byte buffer.
+// Elements of unknown field types are kept undecoded with
+// values of Raw type, so they will be encoded back unchanged.
 // On error returns non nil value with all successfully decoded
 // elements.
+// See DecodeOptions for decoding of untrusted input.
 func DecodeList(bytes []byte) (List, error) {
-	res := List{}
-	for 0 < len(bytes) {
-		elem, tail, err := scan(bytes)
-		if err != nil {
-			return res, err
+	return noLimits.DecodeList(bytes)
+}
+
+// Decode data from byte buffer as DecodeList does, but return
+// elements of unknown field types as a separate list.
+// Values of such elements are of Raw type.
+func DecodeListWithUnknown(bytes []byte) (res List, unknown List, err error) {
+	res = List{}
+	c := NewCursor(bytes)
+	for _, elem := range c.elems() {
+		if _, ok := elem.Value.(Raw); ok {
+			unknown = append(unknown, elem)
+		} else {
+			res = append(res, elem)
 		}
-		res = append(res, elem)
-		bytes = tail
 	}
-	return res, nil
+	return res, unknown, c.Err()
 }
 
 // Convert list of elements to dict of elements.
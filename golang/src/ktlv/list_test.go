@@ -25,6 +25,24 @@ func encdec(t *testing.T, data0 List) {
 			t.Fatalf("elems differ: %v and %v", e0, e1)
 		}
 	}
+	// test JSON round trip
+	encoded, err := ToJSON(data0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data1, err = FromJSON(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data0) != len(data1) {
+		t.Fatalf("encdec: json: origin data len is %v but"+
+			" transcoded data len is %v", len(data0), len(data1))
+	}
+	for i := 0; i < len(data0); i++ {
+		if !data0[i].Equals(data1[i]) {
+			t.Fatalf("json elems differ: %v and %v", data0[i], data1[i])
+		}
+	}
 	// test dictionaries
 	ddata0 := data0.Dict()
 	bytes, err = ddata0.Encode()
@@ -106,6 +124,22 @@ func TestInt24(t *testing.T) {
 		&Elem{6, Int24, Max_Int24}})
 }
 
+func TestEncodeOverflow(t *testing.T) {
+	testset := []*Elem{
+		&Elem{1, Uint24, Max_Uint24 + 1},
+		&Elem{2, Int24, Min_Int24 - 1},
+		&Elem{3, Int24, Max_Int24 + 1},
+		&Elem{4, List_of_Uint24, []uint32{0, 0x1234567}},
+		&Elem{5, List_of_Int24, []int32{Min_Int24 - 1}},
+		&Elem{6, Object, List{&Elem{1, Uint24, Max_Uint32}}},
+	}
+	for n, test := range testset {
+		if _, err := test.Encode(); err == nil {
+			t.Errorf("#%d> expected error but encode succeeded", n)
+		}
+	}
+}
+
 func TestInt32(t *testing.T) {
 	encdec(t, List{
 		&Elem{1, Int32, Min_Int32},
@@ -263,3 +297,155 @@ func TestListOfInt64(t *testing.T) {
 		&Elem{5, List_of_Int64, []int64{1, -2, 3}},
 		&Elem{6, List_of_Int64, []int64{Min_Int64, 0, Max_Int64}}})
 }
+
+func TestUnknownFType(t *testing.T) {
+	known, err := List{
+		&Elem{1, Uint8, uint8(1)},
+		&Elem{3, String, "abc"},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// element #2 of unknown type 200 with 3 bytes long body
+	encoded := append([]byte{}, known[:6]...)
+	encoded = append(encoded, 0, 2, 200, 0, 3, 7, 8, 9)
+	encoded = append(encoded, known[6:]...)
+	expect := &Elem{2, 200, Raw{7, 8, 9}}
+	list, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || !list[1].Equals(expect) {
+		t.Fatalf("unexpected decode result: %v", list)
+	}
+	list, unknown, err := DecodeListWithUnknown(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || len(unknown) != 1 || !unknown[0].Equals(expect) {
+		t.Fatalf("unexpected decode result: %v %v", list, unknown)
+	}
+	dict, unknown, err := DecodeDictWithUnknown(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dict) != 2 || len(unknown) != 1 || !unknown[0].Equals(expect) {
+		t.Fatalf("unexpected decode result: %v %v", dict, unknown)
+	}
+	if elem, err := Search(encoded, 3, 3); err != nil || elem == nil ||
+		elem.Value != "abc" {
+		t.Fatalf("unexpected search result: %v (%v)", elem, err)
+	}
+	if elem, err := DecodeElem(encoded, 2); err != nil ||
+		!elem.Equals(expect) {
+		t.Fatalf("unexpected decode result: %v (%v)", elem, err)
+	}
+}
+
+func TestUnknownFTypeReencode(t *testing.T) {
+	encoded := []byte{
+		0, 1, Uint8, 0, 1, 5,
+		0, 2, 200, 0, 3, 7, 8, 9,
+		0, 3, 201, 0, 0,
+	}
+	list, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list[0].Value = uint8(6)
+	reencoded, err := list.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded[5] = 6
+	if string(reencoded) != string(encoded) {
+		t.Fatalf("expected %v but %v found", encoded, reencoded)
+	}
+	dict, err := DecodeDict(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencoded, err = dict.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	redecoded, err := DecodeDict(reencoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range dict {
+		if !v.Equals(redecoded[k]) {
+			t.Fatalf("dict elems differ: %v and %v", v, redecoded[k])
+		}
+	}
+	if _, err := (&Elem{1, Uint8, Raw{1}}).Encode(); err == nil {
+		t.Errorf("expected error for raw value of known type")
+	}
+}
+
+func TestObject(t *testing.T) {
+	encdec(t, List{
+		&Elem{1, Object, List{}},
+		&Elem{2, Object, List{
+			&Elem{1, Uint8, uint8(1)},
+			&Elem{2, String, "abc"}}},
+		&Elem{3, Object, List{
+			&Elem{1, Object, List{
+				&Elem{1, List_of_Int16, []int16{-1, 1}}}}}}})
+	encoded, err := List{&Elem{1, Object, Dict{
+		5: &Elem{5, Bool, true}}}}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &Elem{1, Object, List{&Elem{5, Bool, true}}}
+	if len(list) != 1 || !list[0].Equals(expect) {
+		t.Fatalf("unexpected decode result: %v", list)
+	}
+}
+
+func TestListOfObject(t *testing.T) {
+	encdec(t, List{
+		&Elem{1, List_of_Object, nil},
+		&Elem{1, List_of_Object, []List{}},
+		&Elem{2, List_of_Object, []List{{}}},
+		&Elem{3, List_of_Object, []List{
+			{&Elem{1, Uint8, uint8(1)}},
+			{},
+			{&Elem{1, String, "a"}, &Elem{2, Bitmap, []bool{true}}}}}})
+	encoded, err := List{&Elem{1, List_of_Object, []Dict{
+		{5: &Elem{5, Bool, true}}}}}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &Elem{1, List_of_Object, []List{{&Elem{5, Bool, true}}}}
+	if len(list) != 1 || !list[0].Equals(expect) {
+		t.Fatalf("unexpected decode result: %v", list)
+	}
+	dicts := []*Elem{
+		{1, List_of_Object, []Dict{{5: &Elem{5, Bool, true}}}},
+		{1, List_of_Object, []Dict{{5: &Elem{5, Bool, false}}}},
+		{1, List_of_Object, []Dict{{6: &Elem{6, Bool, true}}}},
+		{1, List_of_Object, []Dict{}},
+	}
+	for i, e1 := range dicts {
+		for j, e2 := range dicts {
+			if e1.Equals(e2) != (i == j) {
+				t.Errorf("#%d, #%d> unexpected Equals result", i, j)
+			}
+		}
+	}
+	if !dicts[0].Equals(expect) || !expect.Equals(dicts[0]) {
+		t.Errorf("expected []Dict and []List values to be equal")
+	}
+	if _, err := DecodeList([]byte{0, 1, List_of_Object, 0, 1, 0}); err == nil {
+		t.Errorf("expected error for broken List_of_Object")
+	}
+}
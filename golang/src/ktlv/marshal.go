@@ -0,0 +1,272 @@
+package ktlv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Go types used for element values of each field type.
+var goTypes = map[uint8]reflect.Type{
+	Bool:           reflect.TypeOf(false),
+	Uint8:          reflect.TypeOf(uint8(0)),
+	Uint16:         reflect.TypeOf(uint16(0)),
+	Uint24:         reflect.TypeOf(uint32(0)),
+	Uint32:         reflect.TypeOf(uint32(0)),
+	Uint64:         reflect.TypeOf(uint64(0)),
+	Double:         reflect.TypeOf(float64(0)),
+	String:         reflect.TypeOf(""),
+	Bitmap:         reflect.TypeOf([]bool{}),
+	Int8:           reflect.TypeOf(int8(0)),
+	Int16:          reflect.TypeOf(int16(0)),
+	Int24:          reflect.TypeOf(int32(0)),
+	Int32:          reflect.TypeOf(int32(0)),
+	Int64:          reflect.TypeOf(int64(0)),
+	List_of_String: reflect.TypeOf([]string{}),
+	List_of_Uint8:  reflect.TypeOf([]uint8{}),
+	List_of_Uint16: reflect.TypeOf([]uint16{}),
+	List_of_Uint24: reflect.TypeOf([]uint32{}),
+	List_of_Uint32: reflect.TypeOf([]uint32{}),
+	List_of_Uint64: reflect.TypeOf([]uint64{}),
+	List_of_Double: reflect.TypeOf([]float64{}),
+	List_of_Int8:   reflect.TypeOf([]int8{}),
+	List_of_Int16:  reflect.TypeOf([]int16{}),
+	List_of_Int24:  reflect.TypeOf([]int32{}),
+	List_of_Int32:  reflect.TypeOf([]int32{}),
+	List_of_Int64:  reflect.TypeOf([]int64{}),
+	Object:         reflect.TypeOf(List{}),
+	List_of_Object: reflect.TypeOf([]List{}),
+}
+
+// Field types assumed when struct tag omits the type name.
+var kind2t = map[reflect.Kind]uint8{
+	reflect.Bool:    Bool,
+	reflect.Uint8:   Uint8,
+	reflect.Uint16:  Uint16,
+	reflect.Uint32:  Uint32,
+	reflect.Uint64:  Uint64,
+	reflect.Float64: Double,
+	reflect.String:  String,
+	reflect.Int8:    Int8,
+	reflect.Int16:   Int16,
+	reflect.Int32:   Int32,
+	reflect.Int64:   Int64,
+}
+
+// Same as kind2t but for slices, keyed by slice element kind.
+var sliceKind2t = map[reflect.Kind]uint8{
+	reflect.Bool:    Bitmap,
+	reflect.String:  List_of_String,
+	reflect.Uint8:   List_of_Uint8,
+	reflect.Uint16:  List_of_Uint16,
+	reflect.Uint32:  List_of_Uint32,
+	reflect.Uint64:  List_of_Uint64,
+	reflect.Float64: List_of_Double,
+	reflect.Int8:    List_of_Int8,
+	reflect.Int16:   List_of_Int16,
+	reflect.Int32:   List_of_Int32,
+	reflect.Int64:   List_of_Int64,
+}
+
+// Struct field bound to KTLV element.
+type field struct {
+	index  int
+	key    uint16
+	ftype  uint8
+	nested bool // struct (or slice of structs) as nested object
+}
+
+// Encode struct to bytes. Struct fields are mapped to elements
+// with struct tags like `ktlv:"7,uint24"`, where the first item
+// is element key and the second one is field type name (as
+// returned by FTypeToString, case insensitive). When type name
+// is omitted, it is guessed from Go type of the field. Fields
+// without the tag or tagged with `ktlv:"-"` are not encoded.
+// Nested struct fields must be tagged with object type and
+// slices of structs with list_of_object type, so structs like
+// time.Time are not encoded as objects by mistake.
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("marshal: struct expected but %T found", v)
+	}
+	list, err := marshalList(rv)
+	if err != nil {
+		return nil, err
+	}
+	return list.Encode()
+}
+
+// Convert struct to list of elements.
+func marshalList(rv reflect.Value) (List, error) {
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	list := make(List, len(fields))
+	for i, f := range fields {
+		fv := rv.Field(f.index)
+		var value interface{}
+		switch {
+		case f.nested && f.ftype == Object:
+			if value, err = marshalList(fv); err != nil {
+				return nil, err
+			}
+		case f.nested:
+			objects := make([]List, fv.Len())
+			for j := range objects {
+				if objects[j], err = marshalList(fv.Index(j)); err != nil {
+					return nil, err
+				}
+			}
+			value = objects
+		default:
+			value = fv.Convert(goTypes[f.ftype]).Interface()
+		}
+		list[i] = &Elem{f.key, f.ftype, value}
+	}
+	return list, nil
+}
+
+// Decode bytes to struct pointed by v. See Marshal for the
+// description of struct tags. Elements with keys not bound to
+// any struct field are ignored, tagged struct fields without
+// elements are set to zero values.
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() ||
+		rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unmarshal: non-nil struct pointer"+
+			" expected but %T found", v)
+	}
+	dict, err := DecodeDict(b)
+	if err != nil {
+		return err
+	}
+	return unmarshalDict(dict, rv.Elem())
+}
+
+// Set struct fields from dictionary of elements.
+func unmarshalDict(dict Dict, rv reflect.Value) error {
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		elem, ok := dict[f.key]
+		if !ok {
+			fv.Set(reflect.Zero(fv.Type()))
+			continue
+		}
+		if elem.FType != f.ftype {
+			return fmt.Errorf("unmarshal key#%d: %w: %s expected"+
+				" but %s found", f.key, TypeAssertionFailed,
+				FTypeToString(f.ftype), FTypeToString(elem.FType))
+		}
+		switch {
+		case f.nested && f.ftype == Object:
+			if err := unmarshalDict(toDict(elem.Value), fv); err != nil {
+				return err
+			}
+		case f.nested:
+			objects, _ := elem.Value.([]List)
+			fv.Set(reflect.MakeSlice(fv.Type(), len(objects),
+				len(objects)))
+			for i, object := range objects {
+				if err := unmarshalDict(object.Dict(), fv.Index(i)); err != nil {
+					return err
+				}
+			}
+		case elem.Value == nil:
+			fv.Set(reflect.Zero(fv.Type()))
+		default:
+			fv.Set(reflect.ValueOf(elem.Value).Convert(fv.Type()))
+		}
+	}
+	return nil
+}
+
+// Collect struct fields tagged with "ktlv" tag.
+func structFields(t reflect.Type) ([]field, error) {
+	fields := []field{}
+	keys := map[uint16]string{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("ktlv")
+		if !ok || tag == "-" {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return nil, fmt.Errorf("field %s: unexported"+
+				" field can not be tagged", sf.Name)
+		}
+		f, err := parseTag(tag, sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", sf.Name, err)
+		}
+		if other, ok := keys[f.key]; ok {
+			return nil, fmt.Errorf("field %s: key#%d is"+
+				" already used by field %s",
+				sf.Name, f.key, other)
+		}
+		keys[f.key] = sf.Name
+		f.index = i
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Parse struct field tag and check it against field Go type.
+func parseTag(tag string, t reflect.Type) (field, error) {
+	items := strings.Split(tag, ",")
+	if 2 < len(items) {
+		return field{}, fmt.Errorf("bad tag: %#v", tag)
+	}
+	key, err := strconv.ParseUint(strings.TrimSpace(items[0]), 10, 16)
+	if err != nil {
+		return field{}, fmt.Errorf("bad key: %#v", items[0])
+	}
+	var (
+		ftype uint8
+		ok    bool
+	)
+	if len(items) == 2 {
+		ftype, ok = StringToFType(strings.TrimSpace(items[1]))
+		if !ok {
+			return field{}, fmt.Errorf("unknown field type: %#v",
+				items[1])
+		}
+	} else if t.Kind() == reflect.Slice {
+		ftype, ok = sliceKind2t[t.Elem().Kind()]
+	} else {
+		ftype, ok = kind2t[t.Kind()]
+	}
+	if !ok {
+		return field{}, fmt.Errorf("no field type for %s", t)
+	}
+	if ftype == Object && t.Kind() == reflect.Struct ||
+		ftype == List_of_Object && t.Kind() == reflect.Slice &&
+			t.Elem().Kind() == reflect.Struct {
+		return field{key: uint16(key), ftype: ftype, nested: true}, nil
+	}
+	if !compatible(t, goTypes[ftype]) {
+		return field{}, fmt.Errorf("%s is not compatible with %s",
+			t, FTypeToString(ftype))
+	}
+	return field{key: uint16(key), ftype: ftype}, nil
+}
+
+// Check if Go type can be safely converted to another one and
+// back without losing any data.
+func compatible(t1, t2 reflect.Type) bool {
+	if t1.Kind() != t2.Kind() {
+		return false
+	}
+	if t1.Kind() == reflect.Slice {
+		return t1.Elem().Kind() == t2.Elem().Kind() &&
+			t1.ConvertibleTo(t2) && t2.ConvertibleTo(t1)
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package ktlv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type marshalMsg struct {
+	Flag    bool      `ktlv:"1"`
+	Count   uint32    `ktlv:"2,uint24"`
+	Total   uint32    `ktlv:"3"`
+	Name    string    `ktlv:"4,string"`
+	Ratio   float64   `ktlv:"5"`
+	Delta   int32     `ktlv:"6,Int24"`
+	Bits    []bool    `ktlv:"7"`
+	Tags    []string  `ktlv:"8"`
+	Blob    []byte    `ktlv:"9"`
+	Offsets []int32   `ktlv:"10,list_of_int24"`
+	Kind    msgKind   `ktlv:"11"`
+	Skip    string    `ktlv:"-"`
+	Plain   int       // not encoded
+	Values  []float64 `ktlv:"12"`
+}
+
+type msgKind uint8
+
+type marshalOuter struct {
+	ID    uint32         `ktlv:"1"`
+	Inner marshalInner   `ktlv:"2,object"`
+	Items []marshalInner `ktlv:"3,list_of_object"`
+}
+
+type marshalInner struct {
+	Name string `ktlv:"1"`
+	Tags []int8 `ktlv:"2"`
+}
+
+func TestMarshal(t *testing.T) {
+	msg0 := marshalMsg{
+		Flag:    true,
+		Count:   Max_Uint24,
+		Total:   Max_Uint32,
+		Name:    "hello",
+		Ratio:   3.1415927,
+		Delta:   Min_Int24,
+		Bits:    []bool{true, false, true},
+		Tags:    []string{"a", "", "b"},
+		Blob:    []byte{1, 2, 3},
+		Offsets: []int32{-1, 0, 1},
+		Kind:    7,
+		Skip:    "skipped",
+		Plain:   5,
+		Values:  []float64{-1.5},
+	}
+	encoded, err := Marshal(&msg0)
+	if err != nil {
+		t.Fatalf("marshal: %s", err)
+	}
+	dict, err := DecodeDict(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if len(dict) != 12 {
+		t.Errorf("expected 12 elements but %d found", len(dict))
+	}
+	if !dict[2].Equals(&Elem{2, Uint24, Max_Uint24}) {
+		t.Errorf("unexpected element: %v", dict[2])
+	}
+	if !dict[11].Equals(&Elem{11, Uint8, uint8(7)}) {
+		t.Errorf("unexpected element: %v", dict[11])
+	}
+	var msg1 marshalMsg
+	if err := Unmarshal(encoded, &msg1); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+	msg0.Skip = ""
+	msg0.Plain = 0
+	if !reflect.DeepEqual(msg0, msg1) {
+		t.Errorf("expected %+v but %+v found", msg0, msg1)
+	}
+}
+
+func TestMarshalNested(t *testing.T) {
+	msg0 := marshalOuter{
+		ID:    1,
+		Inner: marshalInner{"inner", []int8{-1}},
+		Items: []marshalInner{{"a", nil}, {"b", []int8{1, 2}}},
+	}
+	encoded, err := Marshal(msg0)
+	if err != nil {
+		t.Fatalf("marshal: %s", err)
+	}
+	elem, err := DecodeElem(encoded, 2)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	expect := &Elem{2, Object, List{
+		&Elem{1, String, "inner"},
+		&Elem{2, List_of_Int8, []int8{-1}}}}
+	if !elem.Equals(expect) {
+		t.Errorf("unexpected element: %v", elem)
+	}
+	var msg1 marshalOuter
+	if err := Unmarshal(encoded, &msg1); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+	msg0.Items[0].Tags = []int8{}
+	if !reflect.DeepEqual(msg0, msg1) {
+		t.Errorf("expected %+v but %+v found", msg0, msg1)
+	}
+}
+
+func TestUnmarshalCompat(t *testing.T) {
+	encoded, err := List{
+		&Elem{1, Bool, true},
+		&Elem{100, String, "unknown"},
+		&Elem{3, Uint32, uint32(5)},
+	}.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	// missing keys are zeroed, untagged fields are left intact
+	msg := marshalMsg{Name: "old", Blob: []byte{1}, Skip: "a", Plain: 1}
+	if err := Unmarshal(encoded, &msg); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+	expect := marshalMsg{Flag: true, Total: 5, Skip: "a", Plain: 1}
+	if !reflect.DeepEqual(msg, expect) {
+		t.Errorf("expected %+v but %+v found", expect, msg)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	testset := []interface{}{
+		1,
+		&struct {
+			A int `ktlv:"1"`
+		}{},
+		&struct {
+			A uint16 `ktlv:"1,uint32"`
+		}{},
+		&struct {
+			A uint8 `ktlv:"1,foo"`
+		}{},
+		&struct {
+			A uint8 `ktlv:"65536"`
+		}{},
+		&struct {
+			A uint8 `ktlv:"1"`
+			B uint8 `ktlv:"1"`
+		}{},
+		&struct {
+			a uint8 `ktlv:"1"`
+		}{},
+		&struct {
+			A uint32 `ktlv:"1,uint24"`
+		}{0x1ffffff},
+		&struct {
+			A []int32 `ktlv:"1,list_of_int24"`
+		}{[]int32{Max_Int24 + 1}},
+		&struct {
+			A time.Time `ktlv:"1"`
+		}{},
+		&struct {
+			A marshalInner `ktlv:"1"`
+		}{},
+		&struct {
+			A []marshalInner `ktlv:"1"`
+		}{},
+	}
+	for n, test := range testset {
+		if _, err := Marshal(test); err == nil {
+			t.Errorf("#%d> expected error but marshal succeeded", n)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	encoded, err := List{&Elem{3, String, "abc"}}.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	var msg marshalMsg
+	if err := Unmarshal(encoded, msg); err == nil {
+		t.Errorf("expected error for non-pointer")
+	}
+	if err := Unmarshal(encoded, &msg); err == nil {
+		t.Errorf("expected error for type mismatch")
+	}
+}
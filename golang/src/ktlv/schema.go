@@ -0,0 +1,141 @@
+package ktlv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Expected properties of message field.
+type FieldSpec struct {
+	FType    uint8
+	Required bool
+	// Value used by Schema.Decode when element is missing.
+	// Must be of Go type matching FType (see Elem), see
+	// Schema.Check. Decoded dictionaries get copies of it.
+	Default interface{}
+}
+
+// Message description: field specifications by element keys.
+// Schema should be checked with Check once when it is built:
+// Validate and Decode do not check it again.
+type Schema map[uint16]FieldSpec
+
+// Element found with unexpected field type.
+type TypeMismatch struct {
+	Key      uint16
+	Expected uint8
+	Found    uint8
+}
+
+// Error returned by Schema.Validate. Reports all problems found.
+// Matches ElementNotFound and TypeAssertionFailed with errors.Is.
+type ValidationError struct {
+	// Keys of missing required elements, sorted ascending.
+	Missing []uint16
+	// Elements of unexpected types, sorted by key.
+	Mismatches []TypeMismatch
+}
+
+func (e *ValidationError) Error() string {
+	problems := []string{}
+	if 0 < len(e.Missing) {
+		keys := make([]string, len(e.Missing))
+		for i, key := range e.Missing {
+			keys[i] = fmt.Sprintf("key#%d", key)
+		}
+		problems = append(problems, "missing "+strings.Join(keys, ", "))
+	}
+	for _, m := range e.Mismatches {
+		problems = append(problems, fmt.Sprintf(
+			"key#%d: %s expected but %s found", m.Key,
+			FTypeToString(m.Expected), FTypeToString(m.Found)))
+	}
+	return "validate: " + strings.Join(problems, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	switch target {
+	case ElementNotFound:
+		return 0 < len(e.Missing)
+	case TypeAssertionFailed:
+		return 0 < len(e.Mismatches)
+	}
+	return false
+}
+
+// Check default values of the schema: they must be encodable
+// as elements of their field types.
+func (s Schema) Check() error {
+	for key, spec := range s {
+		if spec.Default == nil {
+			continue
+		}
+		if _, err := appendValue(nil, spec.FType, spec.Default); err != nil {
+			return fmt.Errorf("schema key#%d: bad default: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Check dictionary against schema. Elements with keys not
+// described in the schema are not checked. Returns nil or
+// *ValidationError. Default values are not used.
+func (s Schema) Validate(d Dict) error {
+	res := &ValidationError{}
+	for key, spec := range s {
+		elem, ok := d[key]
+		if !ok {
+			if spec.Required {
+				res.Missing = append(res.Missing, key)
+			}
+			continue
+		}
+		if elem.FType != spec.FType {
+			res.Mismatches = append(res.Mismatches,
+				TypeMismatch{key, spec.FType, elem.FType})
+		}
+	}
+	if len(res.Missing) == 0 && len(res.Mismatches) == 0 {
+		return nil
+	}
+	sort.Slice(res.Missing, func(i, j int) bool {
+		return res.Missing[i] < res.Missing[j]
+	})
+	sort.Slice(res.Mismatches, func(i, j int) bool {
+		return res.Mismatches[i].Key < res.Mismatches[j].Key
+	})
+	return res
+}
+
+// Decode data from byte buffer to dictionary, add missing
+// elements which have default values in the schema and validate
+// the result. On validation error returns the dictionary too.
+// Bad default value is reported when it is applied.
+func (s Schema) Decode(b []byte) (Dict, error) {
+	d, err := DecodeDict(b)
+	if err != nil {
+		return nil, err
+	}
+	for key, spec := range s {
+		if _, ok := d[key]; !ok && spec.Default != nil {
+			// slices must not be shared with the schema
+			value, err := copyValue(spec.FType, spec.Default)
+			if err != nil {
+				return nil, fmt.Errorf("schema key#%d: bad default: %w",
+					key, err)
+			}
+			d.Add(key, spec.FType, value)
+		}
+	}
+	return d, s.Validate(d)
+}
+
+// Make deep copy of element value by encoding and decoding it.
+func copyValue(ftype uint8, value interface{}) (interface{}, error) {
+	body, err := appendValue(nil, ftype, value)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(ftype, body)
+}
@@ -0,0 +1,124 @@
+package ktlv
+
+import (
+	"errors"
+	"testing"
+)
+
+var testSchema = Schema{
+	1: {FType: Uint32, Required: true},
+	2: {FType: String, Required: true},
+	3: {FType: String, Default: "def"},
+	4: {FType: Uint8, Required: true, Default: uint8(4)},
+	5: {FType: Bool},
+}
+
+func TestSchemaValidate(t *testing.T) {
+	err := testSchema.Validate(Dict{
+		2: {2, Uint8, uint8(1)},
+		3: {3, String, "abc"},
+		5: {5, Uint8, uint8(1)},
+		6: {6, Uint8, uint8(1)},
+	})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(verr.Missing) != 2 || verr.Missing[0] != 1 || verr.Missing[1] != 4 {
+		t.Errorf("unexpected missing keys: %v", verr.Missing)
+	}
+	expect := []TypeMismatch{{2, String, Uint8}, {5, Bool, Uint8}}
+	if len(verr.Mismatches) != 2 || verr.Mismatches[0] != expect[0] ||
+		verr.Mismatches[1] != expect[1] {
+		t.Errorf("unexpected mismatches: %v", verr.Mismatches)
+	}
+	if !errors.Is(err, ElementNotFound) || !errors.Is(err, TypeAssertionFailed) {
+		t.Errorf("error does not match sentinels: %s", err)
+	}
+	expectStr := "validate: missing key#1, key#4; " +
+		"key#2: String expected but Uint8 found; " +
+		"key#5: Bool expected but Uint8 found"
+	if err.Error() != expectStr {
+		t.Errorf("unexpected error string: %s", err)
+	}
+	err = testSchema.Validate(Dict{
+		1: {1, Uint32, uint32(1)},
+		2: {2, String, "a"},
+		4: {4, Uint8, uint8(1)},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestSchemaDecode(t *testing.T) {
+	encoded, err := List{
+		&Elem{1, Uint32, uint32(1)},
+		&Elem{2, String, "a"},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := testSchema.Decode(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if len(d) != 4 || d.GetStringDef(3, "") != "def" ||
+		d.GetUint8Def(4, 0) != 4 {
+		t.Errorf("unexpected decode result: %v", d)
+	}
+	encoded, err = List{&Elem{1, String, "a"}}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = testSchema.Decode(encoded)
+	if !errors.Is(err, ElementNotFound) || !errors.Is(err, TypeAssertionFailed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if d == nil || d.GetStringDef(3, "") != "def" {
+		t.Errorf("unexpected decode result: %v", d)
+	}
+}
+
+func TestSchemaDefaults(t *testing.T) {
+	testset := []Schema{
+		{1: {FType: Uint16, Default: uint8(1)}},
+		{1: {FType: Uint24, Default: Max_Uint24 + 1}},
+		{1: {FType: Object, Default: "abc"}},
+	}
+	for n, test := range testset {
+		if err := test.Check(); err == nil {
+			t.Errorf("#%d> expected Check error", n)
+		}
+		if _, err := test.Decode(nil); err == nil {
+			t.Errorf("#%d> expected Decode error", n)
+		}
+		// defaults are not checked again on validation
+		if err := test.Validate(Dict{}); err != nil {
+			t.Errorf("#%d> unexpected Validate error: %s", n, err)
+		}
+	}
+	schema := Schema{
+		1: {FType: List_of_Uint16, Default: []uint16{1, 2}},
+		2: {FType: Object, Default: List{&Elem{1, String, "a"}}},
+	}
+	if err := schema.Check(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	d, err := schema.Decode(nil)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	d[1].Value.([]uint16)[0] = 7
+	d[2].Value.(List)[0].Value = "b"
+	d, err = schema.Decode(nil)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if v := d[1].Value.([]uint16); v[0] != 1 {
+		t.Errorf("default value is modified: %v", v)
+	}
+	if v := d[2].Value.(List); v[0].Value != "a" {
+		t.Errorf("default value is modified: %v", v)
+	}
+}
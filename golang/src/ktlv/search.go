@@ -1,38 +1,85 @@
 package ktlv
 
-import (
-	"errors"
-	"fmt"
-)
-
 // Search specific field in KTLV-encoded message without
-// decoding it to the end.
+// decoding it to the end. At most max elements are examined;
+// elements with other keys are skipped without decoding.
+// Returns nil element and nil error when the field is not found
+// within max elements and io.EOF when the message ends before.
 func Search(encoded []byte, key uint16, max int) (*Elem, error) {
-	for ; 0 < max; max-- {
-		elem, tail, err := scan(encoded)
-		if err != nil {
-			return nil, err
-		}
-		if elem.Key == key {
-			return elem, nil
-		}
-		encoded = tail
-	}
-	return nil, nil
+	return noLimits.Search(encoded, key, max)
 }
 
-// Make search as Search() function does, assume uint64 value.
+// Search specific field within first max elements and first
+// maxBytes bytes of KTLV-encoded message. Zero maxBytes means
+// no byte limit. Elements with other keys are skipped without
+// decoding. Returns ElementNotFound when the field is not found.
+func SearchWithin(encoded []byte, key uint16, max, maxBytes int) (*Elem, error) {
+	return noLimits.SearchWithin(encoded, key, max, maxBytes)
+}
+
+// Make search as SearchWithin() function does without byte
+// limit, assume uint64 value.
 func SearchUint64(encoded []byte, key uint16, max int) (uint64, error) {
-	elem, err := Search(encoded, key, max)
+	return SearchAs[uint64](encoded, key, max, 0)
+}
+
+// Search several fields in KTLV-encoded message in a single
+// pass. Only elements with requested keys are decoded and the
+// search stops as soon as all of them are found. Keys not found
+// in the message are absent in the result. When the message
+// contains several elements with the same key, the first one
+// is returned.
+func SearchKeys(encoded []byte, keys ...uint16) (map[uint16]*Elem, error) {
+	return noLimits.SearchKeys(encoded, keys...)
+}
+
+// Search several fields as SearchKeys does, but call fn for each
+// found element in the message order instead of collecting them
+// to a map. The search stops when fn returns false.
+func SearchKeysFunc(encoded []byte, fn func(*Elem) bool, keys ...uint16) error {
+	return noLimits.SearchKeysFunc(encoded, fn, keys...)
+}
+
+// Search several fields as SearchKeys does, but enforce the limits.
+func (o *DecodeOptions) SearchKeys(encoded []byte, keys ...uint16) (map[uint16]*Elem, error) {
+	res := make(map[uint16]*Elem, len(keys))
+	err := o.SearchKeysFunc(encoded, func(elem *Elem) bool {
+		res[elem.Key] = elem
+		return true
+	}, keys...)
 	if err != nil {
-		return 0, err
-	} else if elem != nil {
-		if n, ok := elem.Value.(uint64); ok {
-			return n, nil
-		} else {
-			return 0, fmt.Errorf("expected uint64 but found: %T",
-				elem.Value)
+		return nil, err
+	}
+	return res, nil
+}
+
+// Search several fields as SearchKeysFunc does, but enforce
+// the limits.
+func (o *DecodeOptions) SearchKeysFunc(encoded []byte, fn func(*Elem) bool, keys ...uint16) error {
+	// keys are usually few, so linear lookup is fine
+	pending := append(make([]uint16, 0, len(keys)), keys...)
+	c := o.NewCursor(encoded)
+	for 0 < len(pending) && c.Next() {
+		found := false
+		for i := 0; i < len(pending); {
+			if pending[i] == c.Key() {
+				found = true
+				pending[i] = pending[len(pending)-1]
+				pending = pending[:len(pending)-1]
+			} else {
+				i++
+			}
+		}
+		if !found {
+			continue
+		}
+		elem, err := c.elem()
+		if err != nil {
+			return err
+		}
+		if !fn(elem) {
+			return nil
 		}
 	}
-	return 0, errors.New("not found")
+	return c.Err()
 }
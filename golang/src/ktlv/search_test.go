@@ -1,6 +1,11 @@
 package ktlv
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestSearchUint64(t *testing.T) {
 	testset := []struct {
@@ -56,3 +61,144 @@ func TestSearchUint64(t *testing.T) {
 		}
 	}
 }
+
+func TestSearchKeys(t *testing.T) {
+	long := strings.Repeat("a", 0x10010)
+	encoded, err := List{
+		&Elem{1, Uint64, uint64(1)},
+		&Elem{2, String, long},
+		&Elem{3, String, "abc"},
+		&Elem{4, Uint8, uint8(4)},
+		&Elem{3, String, "duplicate"},
+		&Elem{5, Bool, true},
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	// stop before broken tail is reached
+	broken := append(append([]byte{}, encoded...), 0, 6, Uint16, 0, 1, 1)
+	testset := []struct {
+		keys   []uint16
+		expect List
+	}{
+		{nil, List{}},
+		{[]uint16{4, 1}, List{&Elem{1, Uint64, uint64(1)}, &Elem{4, Uint8, uint8(4)}}},
+		{[]uint16{3, 3, 7}, List{&Elem{3, String, "abc"}}},
+		{[]uint16{2, 5}, List{&Elem{2, String, long}, &Elem{5, Bool, true}}},
+	}
+	for n, test := range testset {
+		input := broken
+		if len(test.keys) != len(test.expect) {
+			// not all keys can be found
+			input = encoded
+		}
+		res, err := SearchKeys(input, test.keys...)
+		if err != nil {
+			t.Errorf("#%d> unexpected error: %s", n, err)
+			continue
+		}
+		if len(res) != len(test.expect) {
+			t.Errorf("#%d> expected %d elems but %d found",
+				n, len(test.expect), len(res))
+		}
+		for _, elem := range test.expect {
+			if !elem.Equals(res[elem.Key]) {
+				t.Errorf("#%d> unexpected elem: %v", n, res[elem.Key])
+			}
+		}
+	}
+	if _, err := SearchKeys(broken, 6); !errors.Is(err, BadLength) {
+		t.Errorf("expected BadLength but %v found", err)
+	}
+	keys := []uint16{}
+	err = SearchKeysFunc(broken, func(elem *Elem) bool {
+		keys = append(keys, elem.Key)
+		return elem.Key != 3
+	}, 6, 5, 3, 1)
+	if err != nil || len(keys) != 2 || keys[0] != 1 || keys[1] != 3 {
+		t.Errorf("unexpected result: %v (%v)", keys, err)
+	}
+	opts := DecodeOptions{MaxStringLen: 3}
+	if _, err := opts.SearchKeys(encoded, 1, 3); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := opts.SearchKeys(encoded, 2); !errors.Is(err, StringTooLong) {
+		t.Errorf("expected StringTooLong but %v found", err)
+	}
+}
+
+func TestSearchAs(t *testing.T) {
+	long := strings.Repeat("a", 0x10010)
+	encoded, err := List{
+		&Elem{1, Uint64, uint64(1)},  // bytes 0..13
+		&Elem{3, Uint24, uint32(3)},  // bytes 13..21
+		&Elem{4, String, "abc"},      // bytes 21..29
+		&Elem{2, String, long},       // chunked
+		&Elem{5, List_of_Uint8, nil}, // after the chunks
+	}.EncodeChunked()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	testset := []struct {
+		search func() (interface{}, error)
+		expect interface{}
+		err    error
+	}{
+		{func() (interface{}, error) {
+			return SearchAs[uint64](encoded, 1, 1, 0)
+		}, uint64(1), nil},
+		{func() (interface{}, error) {
+			return SearchAs[uint32](encoded, 3, 2, 21)
+		}, uint32(3), nil},
+		{func() (interface{}, error) {
+			return SearchAs[uint32](encoded, 3, 2, 20)
+		}, uint32(0), ElementNotFound},
+		{func() (interface{}, error) {
+			return SearchAs[uint32](encoded, 3, 1, 0)
+		}, uint32(0), ElementNotFound},
+		{func() (interface{}, error) {
+			return SearchAs[string](encoded, 3, 5, 0)
+		}, "", TypeAssertionFailed},
+		{func() (interface{}, error) {
+			return SearchAs[string](encoded, 2, 4, 0)
+		}, long, nil},
+		{func() (interface{}, error) {
+			return SearchAs[[]uint8](encoded, 5, 5, 0)
+		}, []uint8{}, nil},
+		{func() (interface{}, error) {
+			return SearchAs[[]uint8](encoded, 5, 4, 0)
+		}, []uint8(nil), ElementNotFound},
+		{func() (interface{}, error) {
+			return SearchAs[string](encoded, 7, 10, 0)
+		}, "", ElementNotFound},
+		{func() (interface{}, error) {
+			return SearchAs[string](encoded, 4, 0, 0)
+		}, "", ElementNotFound},
+	}
+	for n, test := range testset {
+		val, err := test.search()
+		if !errors.Is(err, test.err) {
+			t.Errorf("#%d> expected error %v but %v found", n, test.err, err)
+		} else if !reflect.DeepEqual(val, test.expect) {
+			t.Errorf("#%d> expected %#v but %#v found", n, test.expect, val)
+		}
+	}
+	// truncated tail is not reached
+	broken := append(append([]byte{}, encoded[:29]...), 0, 6, Uint16, 0, 2, 1)
+	if val, err := SearchAs[string](broken, 4, 10, 0); err != nil || val != "abc" {
+		t.Errorf("unexpected result: %#v (%v)", val, err)
+	}
+	if _, err := SearchAs[string](broken, 7, 10, 0); !errors.Is(err, Truncated) {
+		t.Errorf("expected Truncated but %v found", err)
+	}
+	if _, err := SearchAs[string](broken, 7, 10, 29); !errors.Is(err, ElementNotFound) {
+		t.Errorf("expected ElementNotFound but %v found", err)
+	}
+	opts := DecodeOptions{MaxStringLen: 3}
+	if _, err := opts.SearchWithin(encoded, 5, 5, 0); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := opts.SearchWithin(encoded, 2, 5, 0); !errors.Is(err, StringTooLong) {
+		t.Errorf("expected StringTooLong but %v found", err)
+	}
+}
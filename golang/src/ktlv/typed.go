@@ -0,0 +1,577 @@
+package ktlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Typed element body encoders and decoders. They are used by
+// encodeValue and decodeValue and can be called directly by the
+// code which knows element types in advance (e.g. code generated
+// by ktlvgen) to avoid boxing values into interface{}.
+
+// Append element header to dst. Body length is left zero and
+// must be set later with EndElem. Returns extended slice and
+// offset of the header within it.
+func BeginElem(dst []byte, key uint16, ftype uint8) ([]byte, int) {
+	mark := len(dst)
+	dst = append(dst, uint8(key>>8), uint8(key), ftype, 0, 0)
+	return dst, mark
+}
+
+// Set body length in element header started with BeginElem.
+// Everything appended to dst after the header is treated as
+// element body. Fails with BodyTooLong error when body is
+// longer than 65535 bytes.
+func EndElem(dst []byte, mark int) ([]byte, error) {
+	body_len := len(dst) - mark - 5
+	if maxBodyLen < body_len {
+		return dst, fmt.Errorf("%w: %d bytes", BodyTooLong, body_len)
+	}
+	binary.BigEndian.PutUint16(dst[mark+3:], uint16(body_len))
+	return dst, nil
+}
+
+// Split encoded message to the first element header fields,
+// element body and the rest of the message. Element body is not
+// decoded. Returns io.EOF when b is empty and Truncated error
+// when b ends in the middle of the element (key and ftype are
+// set if the header is complete).
+func ScanRaw(b []byte) (key uint16, ftype uint8, body, tail []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, b, io.EOF
+	}
+	if len(b) < 5 {
+		return 0, 0, nil, b,
+			fmt.Errorf("%w: incomplete element header", Truncated)
+	}
+	key = binary.BigEndian.Uint16(b[0:])
+	ftype = b[2]
+	body_len := int(binary.BigEndian.Uint16(b[3:]))
+	if len(b) < body_len+5 {
+		return key, ftype, nil, nil, fmt.Errorf("%w: expected body"+
+			" len %d but %d found", Truncated, body_len, len(b)-5)
+	}
+	return key, ftype, b[5 : 5+body_len], b[5+body_len:], nil
+}
+
+// Append Bool element body to dst.
+func AppendBool(dst []byte, v bool) []byte {
+	if v {
+		return append(dst, 1)
+	}
+	return append(dst, 0)
+}
+
+// Append Uint8 element body to dst.
+func AppendUint8(dst []byte, v uint8) []byte {
+	return append(dst, v)
+}
+
+// Append Uint16 element body to dst.
+func AppendUint16(dst []byte, v uint16) []byte {
+	return append(dst, uint8(v>>8), uint8(v))
+}
+
+// Append Uint24 element body to dst. Bits of v above the lower
+// 24 are dropped, so v must not exceed Max_Uint24.
+func AppendUint24(dst []byte, v uint32) []byte {
+	return append(dst, uint8(v>>16), uint8(v>>8), uint8(v))
+}
+
+// Append Uint32 element body to dst.
+func AppendUint32(dst []byte, v uint32) []byte {
+	return append(dst, uint8(v>>24), uint8(v>>16), uint8(v>>8),
+		uint8(v))
+}
+
+// Append Uint64 element body to dst.
+func AppendUint64(dst []byte, v uint64) []byte {
+	return append(dst, uint8(v>>56), uint8(v>>48), uint8(v>>40),
+		uint8(v>>32), uint8(v>>24), uint8(v>>16), uint8(v>>8),
+		uint8(v))
+}
+
+// Append Double element body to dst.
+func AppendDouble(dst []byte, v float64) []byte {
+	return AppendUint64(dst, math.Float64bits(v))
+}
+
+// Append String element body to dst.
+func AppendString(dst []byte, v string) []byte {
+	return append(dst, v...)
+}
+
+// Append Bitmap element body to dst.
+func AppendBitmap(dst []byte, v []bool) []byte {
+	l := len(v) / 8
+	rem := len(v) % 8
+	var unused uint8
+	if 0 < rem {
+		l++
+		unused = 8 - uint8(rem)
+	}
+	start := len(dst) + 1
+	dst = append(dst, unused)
+	for i := 0; i < l; i++ {
+		dst = append(dst, 0)
+	}
+	for i, b := range v {
+		if !b {
+			continue
+		}
+		major_bit_offset := int(unused) + i
+		byte_offset := major_bit_offset / 8
+		minor_bit_offset := major_bit_offset % 8
+		mask := uint8(1 << (7 - uint8(minor_bit_offset)))
+		dst[start+byte_offset] |= mask
+	}
+	return dst
+}
+
+// Append Int8 element body to dst.
+func AppendInt8(dst []byte, v int8) []byte {
+	return append(dst, uint8(v))
+}
+
+// Append Int16 element body to dst.
+func AppendInt16(dst []byte, v int16) []byte {
+	return AppendUint16(dst, uint16(v))
+}
+
+// Append Int24 element body to dst. v must be within Min_Int24
+// and Max_Int24, see AppendUint24.
+func AppendInt24(dst []byte, v int32) []byte {
+	return AppendUint24(dst, uint32(v))
+}
+
+// Append Int32 element body to dst.
+func AppendInt32(dst []byte, v int32) []byte {
+	return AppendUint32(dst, uint32(v))
+}
+
+// Append Int64 element body to dst.
+func AppendInt64(dst []byte, v int64) []byte {
+	return AppendUint64(dst, uint64(v))
+}
+
+// Append List_of_String element body to dst.
+func AppendListOfString(dst []byte, v []string) []byte {
+	for _, s := range v {
+		dst = AppendUint16(dst, uint16(len(s)))
+		dst = append(dst, s...)
+	}
+	return dst
+}
+
+// Append List_of_Uint8 element body to dst.
+func AppendListOfUint8(dst []byte, v []uint8) []byte {
+	return append(dst, v...)
+}
+
+// Append List_of_Uint16 element body to dst.
+func AppendListOfUint16(dst []byte, v []uint16) []byte {
+	for _, n := range v {
+		dst = AppendUint16(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Uint24 element body to dst.
+func AppendListOfUint24(dst []byte, v []uint32) []byte {
+	for _, n := range v {
+		dst = AppendUint24(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Uint32 element body to dst.
+func AppendListOfUint32(dst []byte, v []uint32) []byte {
+	for _, n := range v {
+		dst = AppendUint32(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Uint64 element body to dst.
+func AppendListOfUint64(dst []byte, v []uint64) []byte {
+	for _, n := range v {
+		dst = AppendUint64(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Double element body to dst.
+func AppendListOfDouble(dst []byte, v []float64) []byte {
+	for _, n := range v {
+		dst = AppendDouble(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Int8 element body to dst.
+func AppendListOfInt8(dst []byte, v []int8) []byte {
+	for _, n := range v {
+		dst = append(dst, uint8(n))
+	}
+	return dst
+}
+
+// Append List_of_Int16 element body to dst.
+func AppendListOfInt16(dst []byte, v []int16) []byte {
+	for _, n := range v {
+		dst = AppendInt16(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Int24 element body to dst.
+func AppendListOfInt24(dst []byte, v []int32) []byte {
+	for _, n := range v {
+		dst = AppendInt24(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Int32 element body to dst.
+func AppendListOfInt32(dst []byte, v []int32) []byte {
+	for _, n := range v {
+		dst = AppendInt32(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Int64 element body to dst.
+func AppendListOfInt64(dst []byte, v []int64) []byte {
+	for _, n := range v {
+		dst = AppendInt64(dst, n)
+	}
+	return dst
+}
+
+// Append List_of_Object element body to dst. Fails when an
+// object can't be encoded or is longer than 65535 bytes. On
+// error returns dst unchanged.
+func AppendListOfObject(dst []byte, v []List) ([]byte, error) {
+	res := dst
+	var err error
+	for _, object := range v {
+		if res, err = appendObject(res, object); err != nil {
+			return dst, err
+		}
+	}
+	return res, nil
+}
+
+// Decode Bool element body.
+func DecodeBool(b []byte) (bool, error) {
+	if len(b) != 1 {
+		return false, fmt.Errorf("%w: Bool len %d", BadLength, len(b))
+	}
+	return b[0] == 1, nil
+}
+
+// Decode Uint8 element body.
+func DecodeUint8(b []byte) (uint8, error) {
+	if len(b) != 1 {
+		return 0, fmt.Errorf("%w: Uint8 len %d", BadLength, len(b))
+	}
+	return b[0], nil
+}
+
+// Decode Uint16 element body.
+func DecodeUint16(b []byte) (uint16, error) {
+	if len(b) != 2 {
+		return 0, fmt.Errorf("%w: Uint16 len %d", BadLength, len(b))
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// Decode Uint24 element body.
+func DecodeUint24(b []byte) (uint32, error) {
+	if len(b) != 3 {
+		return 0, fmt.Errorf("%w: Uint24 len %d", BadLength, len(b))
+	}
+	return dec_uint24(b), nil
+}
+
+// Decode Uint32 element body.
+func DecodeUint32(b []byte) (uint32, error) {
+	if len(b) != 4 {
+		return 0, fmt.Errorf("%w: Uint32 len %d", BadLength, len(b))
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// Decode Uint64 element body.
+func DecodeUint64(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("%w: Uint64 len %d", BadLength, len(b))
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// Decode Double element body.
+func DecodeDouble(b []byte) (float64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("%w: Double len %d", BadLength, len(b))
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+// Decode String element body.
+func DecodeString(b []byte) (string, error) {
+	return string(b), nil
+}
+
+// Decode Bitmap element body.
+func DecodeBitmap(b []byte) ([]bool, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: Bitmap len %d", BadLength, len(b))
+	}
+	unused := b[0]
+	bit_len := (len(b)-1)*8 - int(unused)
+	if 7 < unused || bit_len < 0 {
+		return nil, fmt.Errorf("%w: Bitmap unused bits %d", Malformed, unused)
+	}
+	r := make([]bool, bit_len)
+	for i := 0; i < len(r); i++ {
+		major_bit_offset := int(unused) + i
+		byte_offset := major_bit_offset / 8
+		minor_bit_offset := major_bit_offset % 8
+		mask := uint8(1 << (7 - uint8(minor_bit_offset)))
+		r[i] = 0 < b[byte_offset+1]&mask
+	}
+	return r, nil
+}
+
+// Decode Int8 element body.
+func DecodeInt8(b []byte) (int8, error) {
+	if len(b) != 1 {
+		return 0, fmt.Errorf("%w: Int8 len %d", BadLength, len(b))
+	}
+	return int8(b[0]), nil
+}
+
+// Decode Int16 element body.
+func DecodeInt16(b []byte) (int16, error) {
+	if len(b) != 2 {
+		return 0, fmt.Errorf("%w: Int16 len %d", BadLength, len(b))
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+// Decode Int24 element body.
+func DecodeInt24(b []byte) (int32, error) {
+	if len(b) != 3 {
+		return 0, fmt.Errorf("%w: Int24 len %d", BadLength, len(b))
+	}
+	return dec_int24(b), nil
+}
+
+// Decode Int32 element body.
+func DecodeInt32(b []byte) (int32, error) {
+	if len(b) != 4 {
+		return 0, fmt.Errorf("%w: Int32 len %d", BadLength, len(b))
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+// Decode Int64 element body.
+func DecodeInt64(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("%w: Int64 len %d", BadLength, len(b))
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// Decode Object element body. Offsets of returned *DecodeError
+// are relative to b. Chunked values are not allowed in nested
+// objects (see Elem.EncodeChunked), so Chunk elements are
+// reported as malformed.
+func DecodeObject(b []byte) (List, error) {
+	res, err := noLimits.decodeObject(b)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Decode List_of_String element body.
+func DecodeListOfString(b []byte) ([]string, error) {
+	return decodeListOfString(b, func(b []byte) string { return string(b) })
+}
+
+// Decode List_of_String element body with given item converter.
+func decodeListOfString(b []byte, toString func([]byte) string) ([]string, error) {
+	res := make([]string, 0)
+	tail := b
+	for 0 < len(tail) {
+		if len(tail) < 2 {
+			return nil, fmt.Errorf("%w: List_of_String item length", BadLength)
+		}
+		l := int(binary.BigEndian.Uint16(tail))
+		if len(tail) < 2+l {
+			return nil, fmt.Errorf("%w: List_of_String item", BadLength)
+		}
+		res = append(res, toString(tail[2:2+l]))
+		tail = tail[2+l:]
+	}
+	return res, nil
+}
+
+// Decode List_of_Uint8 element body. Returned slice shares
+// memory with b.
+func DecodeListOfUint8(b []byte) ([]uint8, error) {
+	return []uint8(b), nil
+}
+
+// Decode List_of_Uint16 element body.
+func DecodeListOfUint16(b []byte) ([]uint16, error) {
+	if len(b)%2 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Uint16 len %d", BadLength, len(b))
+	}
+	r := make([]uint16, len(b)/2)
+	for i := 0; i < len(r); i++ {
+		r[i] = binary.BigEndian.Uint16(b[i*2 : (i+1)*2])
+	}
+	return r, nil
+}
+
+// Decode List_of_Uint24 element body.
+func DecodeListOfUint24(b []byte) ([]uint32, error) {
+	if len(b)%3 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Uint24 len %d", BadLength, len(b))
+	}
+	r := make([]uint32, len(b)/3)
+	for i := 0; i < len(r); i++ {
+		r[i] = dec_uint24(b[i*3 : (i+1)*3])
+	}
+	return r, nil
+}
+
+// Decode List_of_Uint32 element body.
+func DecodeListOfUint32(b []byte) ([]uint32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Uint32 len %d", BadLength, len(b))
+	}
+	r := make([]uint32, len(b)/4)
+	for i := 0; i < len(r); i++ {
+		r[i] = binary.BigEndian.Uint32(b[i*4 : (i+1)*4])
+	}
+	return r, nil
+}
+
+// Decode List_of_Uint64 element body.
+func DecodeListOfUint64(b []byte) ([]uint64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Uint64 len %d", BadLength, len(b))
+	}
+	r := make([]uint64, len(b)/8)
+	for i := 0; i < len(r); i++ {
+		r[i] = binary.BigEndian.Uint64(b[i*8 : (i+1)*8])
+	}
+	return r, nil
+}
+
+// Decode List_of_Double element body.
+func DecodeListOfDouble(b []byte) ([]float64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Double len %d", BadLength, len(b))
+	}
+	r := make([]float64, len(b)/8)
+	for i := 0; i < len(r); i++ {
+		r[i] = math.Float64frombits(binary.BigEndian.Uint64(b[i*8 : (i+1)*8]))
+	}
+	return r, nil
+}
+
+// Decode List_of_Int8 element body.
+func DecodeListOfInt8(b []byte) ([]int8, error) {
+	r := make([]int8, len(b))
+	for i, n := range b {
+		r[i] = int8(n)
+	}
+	return r, nil
+}
+
+// Decode List_of_Int16 element body.
+func DecodeListOfInt16(b []byte) ([]int16, error) {
+	if len(b)%2 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Int16 len %d", BadLength, len(b))
+	}
+	r := make([]int16, len(b)/2)
+	for i := 0; i < len(r); i++ {
+		r[i] = int16(binary.BigEndian.Uint16(b[i*2 : (i+1)*2]))
+	}
+	return r, nil
+}
+
+// Decode List_of_Int24 element body.
+func DecodeListOfInt24(b []byte) ([]int32, error) {
+	if len(b)%3 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Int24 len %d", BadLength, len(b))
+	}
+	r := make([]int32, len(b)/3)
+	for i := 0; i < len(r); i++ {
+		r[i] = dec_int24(b[i*3 : (i+1)*3])
+	}
+	return r, nil
+}
+
+// Decode List_of_Int32 element body.
+func DecodeListOfInt32(b []byte) ([]int32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Int32 len %d", BadLength, len(b))
+	}
+	r := make([]int32, len(b)/4)
+	for i := 0; i < len(r); i++ {
+		r[i] = int32(binary.BigEndian.Uint32(b[i*4 : (i+1)*4]))
+	}
+	return r, nil
+}
+
+// Decode List_of_Int64 element body.
+func DecodeListOfInt64(b []byte) ([]int64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("%w: List_of_Int64 len %d", BadLength, len(b))
+	}
+	r := make([]int64, len(b)/8)
+	for i := 0; i < len(r); i++ {
+		r[i] = int64(binary.BigEndian.Uint64(b[i*8 : (i+1)*8]))
+	}
+	return r, nil
+}
+
+// Decode List_of_Object element body. Offsets of returned
+// *DecodeError are relative to b.
+func DecodeListOfObject(b []byte) ([]List, error) {
+	return decodeListOfObject(b, DecodeObject)
+}
+
+// Decode List_of_Object element body with given object decoder.
+func decodeListOfObject(b []byte, decodeObject func([]byte) (List, error)) ([]List, error) {
+	res := make([]List, 0)
+	tail := b
+	for 0 < len(tail) {
+		if len(tail) < 2 {
+			return nil, fmt.Errorf("%w: List_of_Object item length", BadLength)
+		}
+		l := int(binary.BigEndian.Uint16(tail))
+		if len(tail) < 2+l {
+			return nil, fmt.Errorf("%w: List_of_Object item", BadLength)
+		}
+		object, err := decodeObject(tail[2 : 2+l])
+		if err != nil {
+			if err, ok := err.(*DecodeError); ok {
+				err.Offset += len(b) - len(tail) + 2
+			}
+			return nil, err
+		}
+		res = append(res, object)
+		tail = tail[2+l:]
+	}
+	return res, nil
+}
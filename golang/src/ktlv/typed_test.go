@@ -0,0 +1,69 @@
+package ktlv
+
+import "testing"
+
+func TestAppendElem(t *testing.T) {
+	var (
+		b    []byte
+		mark int
+		err  error
+	)
+	b, mark = BeginElem(b, 1, Uint24)
+	b = AppendUint24(b, 0x123456)
+	if b, err = EndElem(b, mark); err != nil {
+		t.Fatalf("end elem: %s", err)
+	}
+	b, mark = BeginElem(b, 2, List_of_String)
+	b = AppendListOfString(b, []string{"a", "bc"})
+	if b, err = EndElem(b, mark); err != nil {
+		t.Fatalf("end elem: %s", err)
+	}
+	expect, err := List{
+		&Elem{1, Uint24, uint32(0x123456)},
+		&Elem{2, List_of_String, []string{"a", "bc"}},
+	}.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	if string(b) != string(expect) {
+		t.Fatalf("expected %v but %v found", expect, b)
+	}
+	key, ftype, body, tail, err := ScanRaw(b)
+	if err != nil {
+		t.Fatalf("scan: %s", err)
+	}
+	if key != 1 || ftype != Uint24 || len(body) != 3 || len(tail) != 12 {
+		t.Errorf("unexpected header: %d %d %v %v", key, ftype, body, tail)
+	}
+	if n, err := DecodeUint24(body); err != nil || n != 0x123456 {
+		t.Errorf("unexpected value: %#x (%v)", n, err)
+	}
+	if _, _, _, _, err := ScanRaw(b[:4]); err == nil {
+		t.Errorf("expected error for incomplete header")
+	}
+	if _, _, _, _, err := ScanRaw(b[:7]); err == nil {
+		t.Errorf("expected error for incomplete body")
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	testset := [][]byte{
+		{0, 1, Bitmap, 0, 0},
+		{0, 1, Bitmap, 0, 1, 1},
+		{0, 1, Bitmap, 0, 1, 9},
+		{0, 1, Bitmap, 0, 2, 8, 0},
+		{0, 1, Bitmap, 0, 2, 255, 0},
+		{0, 1, Chunk, 0, 4, Chunk, 1, 0, 0},
+		{0, 1, List_of_String, 0, 3, 0, 2, 0},
+		{0, 1, List_of_Uint16, 0, 3, 0, 0, 0},
+		{0, 1, Object, 0, 3, 0, 1, 0},
+	}
+	for n, test := range testset {
+		if _, err := DecodeList(test); err == nil {
+			t.Errorf("#%d> expected error", n)
+		}
+	}
+	if bits, err := DecodeBitmap([]byte{7, 1}); err != nil || len(bits) != 1 || !bits[0] {
+		t.Errorf("unexpected Bitmap: %v (%v)", bits, err)
+	}
+}
@@ -0,0 +1,50 @@
+package ktlv
+
+import "unsafe"
+
+// Decode element value from byte slice as decodeValue does, but
+// make String and List_of_String values (including ones of nested
+// objects) share memory with b.
+// See DecodeOptions.ZeroCopy.
+func decodeValueZeroCopy(t uint8, b []byte) (interface{}, error) {
+	switch t {
+	case String:
+		return aliasString(b), nil
+	case List_of_String:
+		v, err := decodeListOfString(b, aliasString)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case Object:
+		v, err := decodeObjectZeroCopy(b)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case List_of_Object:
+		v, err := decodeListOfObject(b, decodeObjectZeroCopy)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return decodeValue(t, b)
+}
+
+// Same as DecodeObject but with DecodeOptions.ZeroCopy set.
+func decodeObjectZeroCopy(b []byte) (List, error) {
+	res, err := zeroCopy.decodeObject(b)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Options used to decode nested objects in zero-copy mode.
+var zeroCopy = &DecodeOptions{ZeroCopy: true}
+
+// Make string sharing memory with b.
+func aliasString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
@@ -0,0 +1,96 @@
+package ktlv
+
+import (
+	"strings"
+	"testing"
+)
+
+// Log record like message for benchmarks.
+func logRecord() List {
+	return List{
+		&Elem{1, Uint64, uint64(1700000000000)},
+		&Elem{2, String, "info"},
+		&Elem{3, String, "frontend-7f9c4d"},
+		&Elem{4, String, strings.Repeat("request served in 12ms; ", 8)},
+		&Elem{5, List_of_String, []string{"http", "GET", "/api/v1/items", "200"}},
+		&Elem{6, List_of_Uint8, make([]uint8, 64)},
+		&Elem{7, Object, List{
+			&Elem{1, String, "trace-0123456789abcdef"},
+			&Elem{2, String, "span-0123456789"}}},
+	}
+}
+
+func TestZeroCopy(t *testing.T) {
+	list := logRecord()
+	encoded, err := list.Encode()
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	opts := DecodeOptions{ZeroCopy: true}
+	decoded, err := opts.DecodeList(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	copied, err := DecodeList(encoded)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	for i, elem := range list {
+		if !elem.Equals(decoded[i]) || !elem.Equals(copied[i]) {
+			t.Errorf("elems #%d differ", i)
+		}
+	}
+	// aliased values follow changes of the input buffer
+	for i := range encoded {
+		if encoded[i] == 'i' {
+			encoded[i] = 'I'
+		}
+	}
+	if v := decoded[1].Value.(string); v != "Info" {
+		t.Errorf("expected aliased string but %#v found", v)
+	}
+	if v := decoded[4].Value.([]string)[2]; v != "/apI/v1/Items" {
+		t.Errorf("expected aliased string but %#v found", v)
+	}
+	if v := decoded[6].Value.(List)[1].Value.(string); v != "span-0123456789" {
+		t.Errorf("unexpected nested string %#v", v)
+	}
+	encoded[len(encoded)-1] = 'X'
+	if v := decoded[6].Value.(List)[1].Value.(string); v != "span-012345678X" {
+		t.Errorf("expected aliased nested string but %#v found", v)
+	}
+	for i, elem := range list {
+		if !elem.Equals(copied[i]) {
+			t.Errorf("copied elems #%d differ", i)
+		}
+	}
+}
+
+func BenchmarkDecodeList(b *testing.B) {
+	encoded, err := logRecord().Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(encoded)))
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeList(encoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeListZeroCopy(b *testing.B) {
+	encoded, err := logRecord().Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	opts := DecodeOptions{ZeroCopy: true}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(encoded)))
+	for i := 0; i < b.N; i++ {
+		if _, err := opts.DecodeList(encoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -21,6 +21,7 @@ INT16 = 10
 INT24 = 11
 INT32 = 12
 INT64 = 13
+OBJECT = 14
 LIST_OF_STRING = 50
 LIST_OF_UINT8 = 51
 LIST_OF_UINT16 = 52
@@ -33,6 +34,11 @@ LIST_OF_INT16 = 58
 LIST_OF_INT24 = 59
 LIST_OF_INT32 = 60
 LIST_OF_INT64 = 61
+LIST_OF_OBJECT = 62
+
+# Reserved: service type of values split to several elements
+# by the Go codec (see the top-level README). Do not reuse.
+CHUNK = 100
 
 
 MIN_INT8 = -0x80
@@ -178,6 +184,8 @@ def dec_elem(dtype, binary):
             if bitpointer == 8:
                 b = None
         return result
+    elif dtype == OBJECT:
+        return dec(binary)
     elif dtype == LIST_OF_STRING:
         result = []
         while binary:
@@ -185,6 +193,13 @@ def dec_elem(dtype, binary):
             result.append(binary[2:2 + length])
             binary = binary[2 + length:]
         return result
+    elif dtype == LIST_OF_OBJECT:
+        result = []
+        while binary:
+            length = struct.unpack('>H', binary[:2])[0]
+            result.append(dec(binary[2:2 + length]))
+            binary = binary[2 + length:]
+        return result
     elif dtype == LIST_OF_UINT8:
         return list(struct.unpack('>' + 'B' * len(binary), binary))
     elif dtype == LIST_OF_UINT16:
@@ -257,8 +272,13 @@ def enc_elem(dtype, val):
         return struct.pack('>d', val)
     elif dtype == STRING:
         return val
+    elif dtype == OBJECT:
+        return enc(val)
     elif dtype == LIST_OF_STRING:
         return ''.join([struct.pack('>H', len(e)) + e for e in val])
+    elif dtype == LIST_OF_OBJECT:
+        return ''.join([struct.pack('>H', len(e)) + e
+                        for e in map(enc, val)])
     elif dtype == LIST_OF_UINT8:
         return struct.pack('>' + 'B' * len(val), *val)
     elif dtype == LIST_OF_UINT16:
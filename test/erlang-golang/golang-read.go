@@ -1,31 +1,28 @@
 package main
 
 import (
+	"io"
 	"ktlv"
 	"log"
 	"os"
 )
 
 func main() {
-	fileinfo, err := os.Stat("object.bin")
-	if err != nil {
-		log.Fatalf("unable to get file size: %v", err)
-	}
 	file, err := os.Open("object.bin")
 	if err != nil {
 		log.Fatalf("unable to open file: %v", err)
 	}
-	b := make([]byte, fileinfo.Size())
-	n, err := file.Read(b)
-	if err != nil {
-		log.Fatalf("unable to read file: %v", err)
-	}
-	if int64(n) != fileinfo.Size() {
-		log.Fatalf("only %v bytes read but %v expected", n, fileinfo.Size())
-	}
-	data, err := ktlv.DecodeList(b)
-	if err != nil {
-		log.Fatalf("unable to decode: %s", err)
+	defer file.Close()
+	data := ktlv.List{}
+	decoder := ktlv.NewDecoder(file)
+	for {
+		elem, err := decoder.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatalf("unable to decode: %s", err)
+		}
+		data = append(data, elem)
 	}
 
 	expected := ktlv.List{
@@ -153,6 +153,20 @@ class KTLVTest(unittest.TestCase):
         self.enc_dec([], LIST_OF_DOUBLE, [])
         self.enc_dec([-1.0, 0.0, 1.0], LIST_OF_DOUBLE, [-1.0, 0.0, 1.0])
 
+    def test_object(self):
+        self.enc_dec([], OBJECT, [])
+        self.enc_dec([(1, UINT8, 1), (2, STRING, 'abc')],
+                     OBJECT, [(1, UINT8, 1), (2, STRING, 'abc')])
+        self.enc_dec([(1, OBJECT, [(1, BOOL, 1)])],
+                     OBJECT, [(1, OBJECT, [(1, BOOL, 1)])])
+
+    def test_list_of_object(self):
+        self.enc_dec([], LIST_OF_OBJECT, [])
+        self.enc_dec([[]], LIST_OF_OBJECT, [[]])
+        self.enc_dec([[(1, UINT8, 1)], [], [(2, STRING, 'a')]],
+                     LIST_OF_OBJECT,
+                     [[(1, UINT8, 1)], [], [(2, STRING, 'a')]])
+
     def test_main(self):
         self.assertEqual([], dec(enc([])))
         self.assertEqual([(1, BOOL, 1)], dec(enc([(1, BOOL, 1)])))